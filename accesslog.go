@@ -0,0 +1,158 @@
+package slackauth
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+// Access log formats supported by Options.AccessLogFormat.
+const (
+	// AccessLogStructured logs every request with the rest of the structured logs.
+	AccessLogStructured = "structured"
+	// AccessLogCommon writes every request in the Apache common log format.
+	AccessLogCommon = "common"
+	// AccessLogCombined writes every request in the Apache combined log format, which is the
+	// common log format plus the referer and the user agent.
+	AccessLogCombined = "combined"
+)
+
+const accessLogTimeFormat = "02/Jan/2006:15:04:05 -0700"
+
+// ErrUnknownAccessLogFormat is returned when the access log format is not one of the
+// supported formats.
+var ErrUnknownAccessLogFormat = errors.New("slackauth: unknown access log format")
+
+func validateAccessLogFormat(format string) error {
+	switch format {
+	case "", AccessLogStructured, AccessLogCommon, AccessLogCombined:
+		return nil
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownAccessLogFormat, format)
+	}
+}
+
+// statusRecorder records the status and size of the response written through it.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += int64(n)
+	return n, err
+}
+
+// Flush flushes the underlying writer, if it supports it, so streamed responses still work
+// with access logs.
+func (r *statusRecorder) Flush() {
+	flusher, ok := r.ResponseWriter.(http.Flusher)
+	if !ok {
+		return
+	}
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	flusher.Flush()
+}
+
+// Unwrap returns the underlying writer, for http.ResponseController.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// accessLogWriter writes access log lines to a writer.
+type accessLogWriter struct {
+	mut    sync.Mutex
+	format string
+	w      io.Writer
+}
+
+func (aw *accessLogWriter) write(r *http.Request, rec *statusRecorder, start time.Time) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	user := "-"
+	if r.URL.User != nil && r.URL.User.Username() != "" {
+		user = r.URL.User.Username()
+	}
+
+	line := fmt.Sprintf(
+		"%s - %s [%s] \"%s %s %s\" %d %s",
+		host, user, start.Format(accessLogTimeFormat),
+		r.Method, r.URL.RequestURI(), r.Proto,
+		rec.status, accessLogBytes(rec.bytes),
+	)
+	if aw.format == AccessLogCombined {
+		line += fmt.Sprintf(" %q %q", r.Referer(), r.UserAgent())
+	}
+
+	aw.mut.Lock()
+	defer aw.mut.Unlock()
+	if _, err := io.WriteString(aw.w, line+"\n"); err != nil {
+		log15.Error("error writing access log", "step", "access_log", "err", err.Error())
+	}
+}
+
+func accessLogBytes(n int64) string {
+	if n == 0 {
+		return "-"
+	}
+	return fmt.Sprint(n)
+}
+
+// accessLog logs every request handled by h according to the configured access log format,
+// and warns about the slow ones. Structured access logs are sampled like the rest of the
+// high-volume logs, but slow request warnings are not.
+func (s *slackAuth) accessLog(h http.Handler) http.Handler {
+	if s.accessLogs == nil && s.slowRequestThreshold <= 0 {
+		return h
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		h.ServeHTTP(rec, r)
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		s.warnSlowRequest(r, rec, time.Since(start))
+
+		if s.accessLogs == nil {
+			return
+		}
+		if s.accessLogs.format != AccessLogStructured {
+			s.accessLogs.write(r, rec, start)
+		} else if s.sampled() {
+			log15.Info(
+				"request",
+				"step", "access_log",
+				"method", r.Method,
+				"path", r.URL.Path,
+				"status", rec.status,
+				"bytes", rec.bytes,
+				"duration", time.Since(start),
+			)
+		}
+	})
+}
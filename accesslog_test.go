@@ -0,0 +1,62 @@
+package slackauth
+
+import (
+	"bytes"
+	"html/template"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccessLogCommon(t *testing.T) {
+	var buf bytes.Buffer
+	auth := withConfig(&slackAuth{
+		accessLogs: &accessLogWriter{format: AccessLogCommon, w: &buf},
+	}, &config{
+		buttonTpl: template.Must(template.New("button").Parse("button")),
+		errorTpl:  template.Must(template.New("error").Parse(tplError)),
+	})
+
+	r := httptest.NewRequest("GET", "/?foo=bar", nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+	auth.handler().ServeHTTP(httptest.NewRecorder(), r)
+
+	expected := regexp.MustCompile(`^10\.0\.0\.1 - - \[[^\]]+\] "GET /\?foo=bar HTTP/1\.1" 200 6\n$`)
+	assert.Regexp(t, expected, buf.String())
+}
+
+func TestAccessLogCombined(t *testing.T) {
+	var buf bytes.Buffer
+	auth := withConfig(&slackAuth{
+		accessLogs: &accessLogWriter{format: AccessLogCombined, w: &buf},
+	}, &config{
+		errorTpl: template.Must(template.New("error").Parse(tplError)),
+	})
+
+	r := httptest.NewRequest("GET", "/auth", nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+	r.Header.Set("Referer", "https://example.com")
+	r.Header.Set("User-Agent", "test agent")
+	auth.handler().ServeHTTP(httptest.NewRecorder(), r)
+
+	expected := regexp.MustCompile(`^10\.0\.0\.1 - - \[[^\]]+\] "GET /auth HTTP/1\.1" 400 \d+ "https://example\.com" "test agent"\n$`)
+	assert.Regexp(t, expected, buf.String())
+}
+
+func TestAccessLogFormat(t *testing.T) {
+	assert.Nil(t, validateAccessLogFormat(""))
+	assert.Nil(t, validateAccessLogFormat(AccessLogCombined))
+	assert.ErrorIs(t, validateAccessLogFormat("apache"), ErrUnknownAccessLogFormat)
+}
+
+func TestStatusRecorderFlush(t *testing.T) {
+	w := httptest.NewRecorder()
+	rec := &statusRecorder{ResponseWriter: w}
+	assert.Equal(t, w, rec.Unwrap())
+
+	rec.Flush()
+	assert.True(t, w.Flushed)
+	assert.Equal(t, 200, rec.status)
+}
@@ -0,0 +1,35 @@
+package slackauth
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// appIDFormat matches Slack app IDs, such as A0123456789.
+var appIDFormat = regexp.MustCompile(`^A[A-Z0-9]+$`)
+
+// ErrInvalidAppID is returned when the app ID does not look like a Slack app ID.
+var ErrInvalidAppID = errors.New("slackauth: invalid app id")
+
+// validateAppID checks the given app ID has the format of Slack app IDs. An empty app ID is
+// valid, and means it's not sent.
+func validateAppID(appID string) error {
+	if appID != "" && !appIDFormat.MatchString(appID) {
+		return fmt.Errorf("%w: %q", ErrInvalidAppID, appID)
+	}
+	return nil
+}
+
+// ErrWrongApp is returned when the OAuthV2 response is for an app other than the configured
+// AppID.
+var ErrWrongApp = errors.New("slackauth: oauth response is for another app")
+
+// checkAppID checks the given v2 response is for the configured app. Only v2 responses have
+// the app ID, so nothing is checked without one or without a configured app ID.
+func (s *slackAuth) checkAppID(resp *OAuthV2Response) error {
+	if s.appID == "" || resp == nil || resp.AppID == s.appID {
+		return nil
+	}
+	return fmt.Errorf("%w: %q", ErrWrongApp, resp.AppID)
+}
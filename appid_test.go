@@ -0,0 +1,93 @@
+package slackauth
+
+import (
+	"context"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateAppID(t *testing.T) {
+	assert.Nil(t, validateAppID(""))
+	assert.Nil(t, validateAppID("A0123ABC"))
+	assert.True(t, errors.Is(validateAppID("a0123"), ErrInvalidAppID))
+	assert.True(t, errors.Is(validateAppID("A"), ErrInvalidAppID))
+	assert.True(t, errors.Is(validateAppID("A01&scope=admin"), ErrInvalidAppID))
+
+	_, err := New(Options{
+		Addr:         ":8080",
+		ClientID:     "foo",
+		ClientSecret: "bar",
+		AppID:        "app",
+	})
+	assert.True(t, errors.Is(err, ErrInvalidAppID))
+}
+
+func TestAppIDAuthorizeURL(t *testing.T) {
+	auth := withConfig(&slackAuth{
+		clientID:         "foo",
+		authorizeBaseURL: DefaultAuthorizeBaseURL,
+		appID:            "A0123",
+	}, &config{scopes: "bot"})
+	assert.Equal(t, "https://slack.com/oauth/authorize?app_id=A0123&client_id=foo&scope=bot", auth.AuthorizeURL())
+}
+
+func TestAppIDButtonTpl(t *testing.T) {
+	auth := withConfig(&slackAuth{
+		clientID:         "foo",
+		authorizeBaseURL: DefaultAuthorizeBaseURL,
+		appID:            "A0123",
+		stateTTL:         -1,
+	}, &config{
+		scopes:    "bot",
+		buttonTpl: template.Must(template.New("button").Parse("{{.AppID}}")),
+	})
+
+	w := httptest.NewRecorder()
+	auth.handler().ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, "A0123", w.Body.String())
+}
+
+type appIDStub struct {
+	slackAPIMock
+	appID string
+}
+
+func (a *appIDStub) GetOAuthV2Response(ctx context.Context, id, secret, code string, debug bool) (*OAuthV2Response, error) {
+	resp, err := a.slackAPIMock.GetOAuthV2Response(ctx, id, secret, code, debug)
+	if resp != nil {
+		resp.AppID = a.appID
+	}
+	return resp, err
+}
+
+func TestWrongApp(t *testing.T) {
+	api := &appIDStub{appID: "A0123ABC"}
+	auth := withConfig(&slackAuth{
+		auths:        make(chan AuthEvent, 2),
+		api:          api,
+		oauthVersion: OAuthV2,
+		appID:        "A0123ABC",
+	}, &config{
+		successTpl: template.Must(template.New("success").Parse(tplSuccess)),
+		errorTpl:   template.Must(template.New("error").Parse(tplError)),
+	})
+	var handled error
+	auth.OnError(func(err error, r *http.Request) { handled = err })
+
+	w := httptest.NewRecorder()
+	auth.handler().ServeHTTP(w, httptest.NewRequest("GET", "/auth?code=foo", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Nil(t, handled)
+
+	api.appID = "A0456DEF"
+	w = httptest.NewRecorder()
+	auth.handler().ServeHTTP(w, httptest.NewRequest("GET", "/auth?code=foo", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.True(t, errors.Is(handled, ErrWrongApp))
+	assert.Len(t, auth.auths, 1)
+}
@@ -1,14 +1,24 @@
 package slackauth
 
 import (
+	"context"
+	"crypto/x509"
 	"errors"
+	"fmt"
 	"html/template"
 	"io"
 	"io/ioutil"
 	"log"
+	"math/rand"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/nlopes/slack"
@@ -25,63 +35,410 @@ const (
 	COMMANDS = "commands"
 )
 
+const defaultHSTSMaxAge = 365 * 24 * time.Hour
+
 // Service is a service to authenticate on slack using the "Add to slack" button.
 type Service interface {
 	// SetLogOutput sets the place where logs will be written.
 	SetLogOutput(io.Writer)
 
-	// Run will run the service. This method blocks until the service crashes or stops.
+	// SwapLogOutput replaces the place where logs are written, keeping their format, and
+	// returns the previous one. Logs being written during the swap go to either of them, and
+	// once it returns nothing else is written to the previous one, so it can be closed, like
+	// after a log file is rotated.
+	SwapLogOutput(io.Writer) io.Writer
+
+	// Run will run the service. This method blocks until the service crashes or stops. It
+	// returns nil if it was stopped with Stop.
 	Run() error
 
+	// Stop shuts the server down gracefully, waiting for the requests being served, and then
+	// waits for the pending auth events to be handled. If the context expires first, its
+	// error is returned. It can be called several times, even concurrently, and always
+	// returns the result of the first call. Authorizations that come in after Stop get a 503.
+	Stop(ctx context.Context) error
+
+	// Handler returns the handler with all the routes of the service, and starts dispatching
+	// auth events, so the service can be mounted in another server instead of using Run.
+	// None of the options of the server Run starts apply, and the server should be shut
+	// down before calling Stop.
+	Handler() http.Handler
+
 	// OnAuth sets the handler that will be triggered every time someone authorizes slack
 	// successfully.
 	OnAuth(func(*slack.OAuthResponse))
+
+	// OnAuthEvent sets the handler that will be triggered every time someone authorizes slack
+	// successfully, along with some details about where the authorization came from. It can
+	// be used alongside OnAuth.
+	OnAuthEvent(func(AuthEvent))
+
+	// OnAuthContext sets a handler that will be triggered every time someone authorizes slack
+	// successfully. The context is cancelled once the CallbackTimeout is exceeded, in which
+	// case the service stops waiting for the handler and moves on to the next event.
+	OnAuthContext(func(context.Context, AuthEvent) error)
+
+	// OnAuthV2 sets the handler that will be triggered every time someone authorizes slack
+	// successfully using OAuthV2, with the raw v2 response. It can be used alongside the
+	// other handlers, which receive the response in the v1 shape.
+	OnAuthV2(func(*OAuthV2Response))
+
+	// OnError sets the handler that will be triggered every time the authorization fails
+	// because of Slack, either because the user denied it or the exchange failed, because
+	// the ReinstallCooldown rejected it, or because the success page could not be rendered.
+	// It receives the authorization request and runs before the response is written. It's
+	// also triggered, with a nil request, when the OnAuthContext handler fails or times out,
+	// and when the install notification can't be posted.
+	OnError(func(error, *http.Request))
+
+	// OnPartialScope sets a handler that will be triggered when Slack grants fewer scopes than
+	// the ones requested, before the success page is rendered. If the handler returns true it
+	// is expected to have written the response and the success page is not rendered. The
+	// auth event is triggered either way.
+	OnPartialScope(func(granted, requested []string, w http.ResponseWriter, r *http.Request) bool)
+
+	// OnBeforeButton sets a handler that will be triggered before the button page is
+	// rendered, and before the install and consent routes start the install. If it returns
+	// false, the gate page is rendered instead, and if it returns an error, the error page is
+	// rendered and the error handler is triggered.
+	OnBeforeButton(func(*http.Request) (bool, error))
+
+	// HandleFunc registers an extra route on the server, with the same middleware as the
+	// routes of the service. It must be called before Run, and it panics if the pattern is
+	// one of the routes of the service or has already been registered.
+	HandleFunc(pattern string, handler http.HandlerFunc)
+
+	// Installs returns the channel where successful authorizations are delivered when the
+	// InstallQueueSize option is set, or nil otherwise. See Install for the contract every
+	// consumer must follow.
+	Installs() <-chan *Install
+
+	// Stats returns the current counters of the service.
+	Stats() Stats
+
+	// MetricsSnapshot returns the current value of every counter and gauge of the service,
+	// keyed by name, without resetting them.
+	MetricsSnapshot() map[string]float64
+
+	// RecentInstalls returns the last auth events, from the oldest to the newest, without
+	// tokens or the names and emails of the users. It returns nil unless the RecentBufferSize
+	// option is set.
+	RecentInstalls() []AuthEvent
+
+	// HandleCallback exchanges the given authorization code and triggers the auth handler with
+	// the result, just like the authorization route does, but without going through HTTP.
+	// The handler is called synchronously, so this is mostly useful for tests.
+	HandleCallback(ctx context.Context, code string) (*slack.OAuthResponse, error)
+
+	// Replay triggers the auth handler with the OAuth response dumped to the given file when
+	// the DumpDir option is set, without exchanging any code. The handler is called
+	// synchronously, so this is mostly useful during development.
+	Replay(ctx context.Context, file string) error
+
+	// Reload reads all the templates again and starts using them for new requests. If any of
+	// them fails to be read, the current ones are kept.
+	Reload() error
+
+	// SuccessTemplate returns a copy of the current success template, so it can be executed
+	// with sample data in tests.
+	SuccessTemplate() *template.Template
+
+	// ErrorTemplate returns a copy of the current error template.
+	ErrorTemplate() *template.Template
+
+	// ButtonTemplate returns a copy of the current button template, or nil if there is none.
+	ButtonTemplate() *template.Template
+
+	// AuthorizeURL returns the URL users need to visit to authorize the app with the
+	// configured scopes.
+	AuthorizeURL() string
+
+	// SignInURL returns the URL users need to visit to sign in with Slack with the identity
+	// scopes. It returns an empty string unless the IdentityScopes option is set.
+	SignInURL() string
+
+	// Scopes returns the configured scopes, as they will be requested.
+	Scopes() []string
+
+	// UpgradeURL returns an authorize URL requesting only the configured scopes that are not
+	// in the given list of scopes already granted to a team, or an empty string if there is
+	// nothing to upgrade. Unlike a fresh install, Slack merges the new scopes with the
+	// existing grant, so the scope of the resulting OAuth response contains both.
+	UpgradeURL(existingScopes []string) string
 }
 
-type slackAPI interface {
-	GetOAuthResponse(string, string, string, bool) (*slack.OAuthResponse, error)
+// AuthEvent is a successful authorization.
+type AuthEvent struct {
+	// Response is the OAuth response returned by Slack.
+	Response *slack.OAuthResponse
+	// ResponseV2 is the OAuth response returned by Slack when using OAuthV2. Response has
+	// the same data in the v1 shape.
+	ResponseV2 *OAuthV2Response
+	// Source identifies where the authorization came from. It's the Source option, if any,
+	// or the path of the request otherwise.
+	Source string
+	// Request is the authorization request. It's nil if the authorization did not come from
+	// an HTTP request, e.g. when using HandleCallback.
+	Request *http.Request
+	// Params are the signed params of the authorization request, once verified.
+	Params url.Values
+	// Identity is the identity of the user who authorized the app, if identity scopes were
+	// granted.
+	Identity Identity
+	// Reinstall is true if the team had already installed the app, according to the
+	// TokenStore.
+	Reinstall bool
+	// Team is the info of the team the app was installed on, if EnrichTeamInfo is enabled and
+	// Slack returned a bot token.
+	Team *slack.TeamInfo
+	// TeamDomain is the domain of Team, normalized if NormalizeTeamDomain is enabled. The raw
+	// domain is kept in Team.
+	TeamDomain string
+	// Variant is the name of the button variant the user saw before authorizing, if there are
+	// ButtonVariants.
+	Variant string
+	// Context has the values returned by the ContextExtractor for the button request that
+	// led to this authorization, if any. They went through JSON, so numbers are float64.
+	Context map[string]interface{}
+	// IdempotencyKey is the value of the IdempotencyKeyParam signed param of the authorization
+	// request. Since it's only taken from params whose signature was verified, it can be used
+	// to deduplicate installs. It's empty if the option is not set or the param was not sent.
+	IdempotencyKey string
 }
 
-type slackAPIWrapper struct{}
+// SlackAPI is the client used to talk to the Slack API.
+type SlackAPI interface {
+	// GetOAuthResponse exchanges an authorization code for an OAuth response using the given
+	// client ID and client secret.
+	GetOAuthResponse(ctx context.Context, clientID, clientSecret, code string, debug bool) (*slack.OAuthResponse, error)
+	// GetOAuthV2Response exchanges an authorization code for an OAuth v2 response using the
+	// given client ID and client secret.
+	GetOAuthV2Response(ctx context.Context, clientID, clientSecret, code string, debug bool) (*OAuthV2Response, error)
+	// AuthTest checks the given token is valid and returns the identity it belongs to.
+	AuthTest(ctx context.Context, token string) (*slack.AuthTestResponse, error)
+	// GetUserIdentity returns the identity of the user the given token belongs to.
+	GetUserIdentity(ctx context.Context, token string) (*slack.UserIdentityResponse, error)
+	// GetTeamInfo returns the info of the team the given token belongs to.
+	GetTeamInfo(ctx context.Context, token string) (*slack.TeamInfo, error)
+}
+
+// permanentOAuthErrors are the errors returned by Slack during the exchange that will not go
+// away by retrying.
+var permanentOAuthErrors = map[string]bool{
+	"invalid_code":                     true,
+	"code_already_used":                true,
+	"code_expired":                     true,
+	"bad_redirect_uri":                 true,
+	"invalid_client_id":                true,
+	"bad_client_secret":                true,
+	"invalid_grant_type":               true,
+	"oauth_authorization_url_mismatch": true,
+}
+
+func isTransient(err error) bool {
+	return !permanentOAuthErrors[err.Error()]
+}
+
+type slackAPIWrapper struct {
+	client *http.Client
+
+	// exchangeClient, credentialsInHeader and exchangeParams are only used by the exchange,
+	// which goes through the Slack library unless the credentials are sent in the header,
+	// there are extra params or there is an exchange client.
+	exchangeClient      *http.Client
+	credentialsInHeader bool
+	exchangeParams      map[string]string
+}
+
+// newClient returns a Slack client for the given token using the configured HTTP client,
+// if any.
+func (w *slackAPIWrapper) newClient(token string) *slack.Client {
+	if w.client == nil {
+		return slack.New(token)
+	}
+	return slack.New(token, slack.OptionHTTPClient(w.client))
+}
+
+func (w *slackAPIWrapper) GetOAuthResponse(ctx context.Context, id, secret, code string, debug bool) (*slack.OAuthResponse, error) {
+	if w.usesCustomExchange() {
+		return w.customExchange(ctx, id, secret, code)
+	}
 
-func (*slackAPIWrapper) GetOAuthResponse(id, secret, code string, debug bool) (*slack.OAuthResponse, error) {
 	if debug {
 		slack.SetLogger(log.New(os.Stdout, "", log.LstdFlags))
 	}
-	return slack.GetOAuthResponse(id, secret, code, "", debug)
+	return slack.GetOAuthResponseContext(ctx, id, secret, code, "", debug)
+}
+
+func (w *slackAPIWrapper) AuthTest(ctx context.Context, token string) (*slack.AuthTestResponse, error) {
+	return w.newClient(token).AuthTestContext(ctx)
+}
+
+func (w *slackAPIWrapper) GetUserIdentity(ctx context.Context, token string) (*slack.UserIdentityResponse, error) {
+	return w.newClient(token).GetUserIdentityContext(ctx)
+}
+
+func (w *slackAPIWrapper) GetTeamInfo(ctx context.Context, token string) (*slack.TeamInfo, error) {
+	return w.newClient(token).GetTeamInfoContext(ctx)
 }
 
 type slackAuth struct {
+	// authQueueHighWater is accessed atomically, so it's kept first to be 64-bit aligned.
+	authQueueHighWater int64
+	grantedScopes      scopeCounter
+	counters           metricCounters
+
 	clientID     string
 	clientSecret string
 	addr         string
+	unixSocket   string
 	certFile     string
 	keyFile      string
-	successTpl   *template.Template
-	errorTpl     *template.Template
+	clientCAs    *x509.CertPool
 	debug        bool
-	auths        chan *slack.OAuthResponse
+	auths        chan AuthEvent
+	handlersMut  sync.RWMutex
 	callback     func(*slack.OAuthResponse)
-	api          slackAPI
-	buttonTpl    *template.Template
-	scopes       string
+	eventHandler func(AuthEvent)
+	ctxHandler   func(context.Context, AuthEvent) error
+	api          SlackAPI
+	fallbackAPI  SlackAPI
+	cfg          atomic.Pointer[config]
+	opts         Options
+
+	// partialScopeHandler, beforeButtonHandler, routes, v2Handler and errorHandler are also
+	// guarded by handlersMut.
+	partialScopeHandler func(granted, requested []string, w http.ResponseWriter, r *http.Request) bool
+	beforeButtonHandler func(*http.Request) (bool, error)
+	routes              []route
+	v2Handler           func(*OAuthV2Response)
+	errorHandler        func(error, *http.Request)
+
+	maintenanceFile string
+
+	notificationWebhook string
+	postWebhook         func(string, *slack.WebhookMessage) error
+
+	idleTimeout time.Duration
+	conns       *connStats
+	hstsMaxAge  time.Duration
+
+	authorizeBaseURL string
+	store            TokenStore
+	source           string
+	logSampleRate    float64
+	successSampling  float64
+	signer           ParamSigner
+	signedParamNames []string
+	idempotencyParam string
+	buttonFS         http.FileSystem
+	buttonIndex      string
+	limiter          *rateLimiter
+	callbackTimeout  time.Duration
+	installs         chan *Install
+	verifyToken      bool
+
+	successRedirectURL   string
+	successRedirectDelay time.Duration
+
+	creds *credentialsCache
+
+	scopeSets     map[string][]string
+	scopeSetParam string
+	serveManifest bool
+	records       *recordWriter
+
+	reloadOnSIGHUP bool
+	accessLogs     *accessLogWriter
+
+	slowRequestThreshold time.Duration
+
+	recent      *recentInstalls
+	serveRecent bool
+	strictSlash bool
+	optionalBot bool
+	dumpDir     string
+	consent     bool
+
+	debugLogHeaders []string
+	scopeDelimiter  string
+
+	contextExtractor func(*http.Request) map[string]interface{}
+	metricsSink      Metrics
+
+	errorWebhook        string
+	errorWebhookBackoff time.Duration
+	errorQueue          errorQueue
+
+	requireAuthHandler bool
+
+	panicPolicy string
+
+	flags *featureFlagsCache
+
+	plainTextForNonBrowsers bool
+	enrichTeamInfo          bool
+	normalizeTeamDomain     bool
+
+	listenConfig *net.ListenConfig
+
+	buttonAuthRedirect *url.URL
+
+	routeLimiters map[string]*rateLimiter
+
+	identityScopes []string
+
+	stepDefs map[string]string
+
+	stateTTL time.Duration
+
+	logOut logOutput
+
+	// srvMut guards srv, consumerDone and stopped.
+	srvMut       sync.Mutex
+	srv          *http.Server
+	consumerDone chan struct{}
+	stopped      bool
+	stopOnce     sync.Once
+	stopErr      error
+
+	// authsMut guards the sends to auths against closing it, which sets authsClosed.
+	authsMut    sync.RWMutex
+	authsClosed bool
+
+	statuses *installStatuses
+
+	oauthVersion string
+	userScopes   []string
+
+	appID string
+
+	reinstallCooldown time.Duration
+	cooldowns         CooldownStore
 }
 
 // Options has all the configurable parameters for slack authenticator.
 type Options struct {
 	// Addr is the address where the service will run. e.g: :8080, 0.0.0.0:8989, etc.
+	// It's required unless UnixSocket is set.
 	Addr string
+	// UnixSocket is the path of the Unix socket where the service will run instead of Addr.
+	// Any socket file left at that path is removed before listening, and the socket file is
+	// removed when the server stops. Run fails with ErrNotSocket if the path is another file.
+	UnixSocket string
 	// ClientID is the slack client ID provided to you in your app credentials.
 	ClientID string
 	// ClientSecret is the slack client secret provided to you in your app credentials.
 	ClientSecret string
 	// SuccessTpl is the path to the template that will be displayed when there is a successful
-	// auth.
+	// auth. The template receives all the fields of the OAuth response, plus DisplayName,
+	// which is the team name or its ID if the name is empty.
 	SuccessTpl string
 	// ErrorTpl is the path to the template that will be displayed when there is an invalid
-	// auth.
+	// auth. The template receives a description of what went wrong as Error.
 	ErrorTpl string
-	// Debug will print some debug logs.
+	// Debug will print some debug logs, including the raw responses of the token exchange
+	// with secrets redacted.
 	Debug bool
 	// CertFile is the path to the SSL certificate file. If this and KeyFile are provided, the
 	// server will be run with SSL.
@@ -89,79 +446,650 @@ type Options struct {
 	// KeyFile is the path to the SSL certificate key file. If this and CertFile are provided, the
 	// server will be run with SSL.
 	KeyFile string
-	// ButtonTpl is the path to the Slack button template
+	// RequireClientCert makes the server reject, during the TLS handshake, all the clients
+	// without a valid certificate signed by one of the CAs in ClientCAFile. It requires the
+	// server to be run with SSL.
+	RequireClientCert bool
+	// ClientCAFile is the path to the PEM encoded certificates of the CAs used to verify client
+	// certificates when RequireClientCert is set.
+	ClientCAFile string
+	// ButtonTpl is the path to the Slack button template. The template receives the
+	// requested scopes as Scopes, the client ID as ClientId and the full authorize URL
+	// as AuthorizeURL. If it's not provided, the button route responds with a 404.
 	ButtonTpl string
+	// ButtonFS is a filesystem containing the whole install site. If it's provided, ButtonTpl
+	// is ignored, the ButtonIndex file of the filesystem is used as the button template and
+	// every other file is served as is.
+	ButtonFS http.FileSystem
+	// ButtonIndex is the name of the file of ButtonFS used as the button template. Defaults
+	// to index.html.
+	ButtonIndex string
 	// Scopes is the list of the allowed scopes
 	Scopes []string
+	// MaintenanceFile is the path to a flag file. While the file exists, all install routes
+	// will respond with the maintenance page instead of the usual content.
+	MaintenanceFile string
+	// MaintenanceTpl is the path to the template that will be displayed while the service is
+	// under maintenance. If it's not provided, a plain text message will be displayed.
+	MaintenanceTpl string
+	// GateTpl is the path to the template that will be displayed instead of the button when
+	// the OnBeforeButton handler returns false. If it's not provided, a plain text message
+	// will be displayed.
+	GateTpl string
+	// ConsentTpl is the path to a consent page that will be displayed instead of the button.
+	// It receives the same data as the button template, plus ConsentURL, where a form must be
+	// posted once the user accepts to be redirected to Slack. The form can include the scope
+	// set param and, with OptionalBot, the include_bot value.
+	ConsentTpl string
+	// ReinstallTpl is the path to the template that will be displayed instead of the success
+	// page when a team that already installed the app, according to the TokenStore, installs
+	// it again. It receives the same data as the success template. The token is saved either
+	// way.
+	ReinstallTpl string
+	// SuccessContentType is the content type of the success page. Defaults to
+	// DefaultContentType.
+	SuccessContentType string
+	// ErrorContentType is the content type of the error page. Defaults to DefaultContentType.
+	ErrorContentType string
+	// ButtonContentType is the content type of the button page. Defaults to
+	// DefaultContentType.
+	ButtonContentType string
+	// InstallNotificationWebhook is the URL of a Slack incoming webhook. If provided, a message
+	// will be posted to it after every successful install.
+	InstallNotificationWebhook string
+	// IdleTimeout is the maximum amount of time to wait for the next request on a keep-alive
+	// connection before closing it. If it's zero, ReadTimeout is used.
+	IdleTimeout time.Duration
+	// HSTSMaxAge is the max-age sent in the Strict-Transport-Security header when the server
+	// runs with SSL. Defaults to a year if it's zero, and a negative value disables the
+	// header. The header is never sent over plain HTTP.
+	HSTSMaxAge time.Duration
+	// SlackAPIFallback is a Slack client that will be used to retry the exchange when the
+	// default one fails with an error that is not caused by the authorization code itself.
+	SlackAPIFallback SlackAPI
+	// AuthorizeBaseURL is the Slack endpoint users are sent to in order to authorize the app.
+	// Defaults to DefaultAuthorizeBaseURL.
+	AuthorizeBaseURL string
+	// TokenStore is where the OAuth responses of successful installs will be saved. If it's
+	// provided, Run will fail when the store is not reachable.
+	TokenStore TokenStore
+	// Source identifies this service in the auth events, which is useful when several services
+	// share the same auth handler. Defaults to the path of the authorization request.
+	Source string
+	// LogSampleRate is the fraction, between 0 and 1, of button views that will be logged.
+	// Errors and successful installs are never sampled out. If it's zero, all button views
+	// are logged.
+	LogSampleRate float64
+	// SuccessLogSampleRate is the fraction, between 0 and 1, of successful authorizations
+	// whose log line will be written. Errors are never sampled out. If it's zero, all
+	// successful authorizations are logged.
+	SuccessLogSampleRate float64
+	// ParamSigner is used to sign the params listed in SignedParams. If it's provided, the
+	// button template receives the signed params of the button request, along with their
+	// signature, as a query string in SignedParams, so it can be carried through the OAuth
+	// round trip. Authorization requests whose signed params don't verify are rejected.
+	// The scopes requested when the button is displayed, or when users are redirected to
+	// Slack, are also recorded in a signed cookie, and authorizations granting scopes that
+	// were not requested are rejected.
+	ParamSigner ParamSigner
+	// SignedParams is the list of custom params that will be signed by ParamSigner.
+	SignedParams []string
+	// IdempotencyKeyParam is the name of one of the SignedParams whose value will be available
+	// as the IdempotencyKey of the auth event. The param is signed when the button is
+	// rendered, carried through the OAuth round trip and verified before being exposed, so it
+	// requires a ParamSigner.
+	IdempotencyKeyParam string
+	// DebugLogHeaders is the list of request headers, e.g. X-Forwarded-Proto or Host, whose
+	// values will be logged for every authorization request when Debug is set. Anything
+	// resembling a secret is redacted.
+	DebugLogHeaders []string
+	// RateLimit is the maximum number of requests per second the server will accept. Requests
+	// over the limit get a 429 response. If it's zero, requests are not limited.
+	RateLimit float64
+	// RateLimitBurst is the maximum number of requests over the rate limit that will be
+	// accepted at once. Defaults to 1.
+	RateLimitBurst int
+	// CallbackTimeout is the maximum time the handler set with OnAuthContext has to handle an
+	// event. If it's zero, there is no timeout.
+	CallbackTimeout time.Duration
+	// InstallQueueSize enables the Installs channel with the given capacity. When it's enabled
+	// and there is a TokenStore, OAuth responses are only saved once the consumer of the
+	// channel marks the install as done.
+	InstallQueueSize int
+	// VerifyTokenOnInstall will check the access token returned by Slack with auth.test
+	// before considering the install successful.
+	VerifyTokenOnInstall bool
+	// SuccessRedirectURL is the URL users will be redirected to from the success page after
+	// SuccessRedirectDelay. The redirection is done with a Refresh header, and both values are
+	// available in the success template as RedirectURL and RedirectDelay, in seconds.
+	SuccessRedirectURL string
+	// SuccessRedirectDelay is the time users will see the success page before being
+	// redirected to SuccessRedirectURL.
+	SuccessRedirectDelay time.Duration
+	// CredentialsProvider returns the client ID and client secret to use, so they can be
+	// rotated without restarting the service. If it's provided, ClientID and ClientSecret are
+	// only used as a fallback for the client ID shown in the button.
+	CredentialsProvider CredentialsProvider
+	// CredentialsTTL is the time the credentials returned by CredentialsProvider are cached.
+	// Defaults to a minute.
+	CredentialsTTL time.Duration
+	// ScopeSets are alternative sets of scopes, by name, that can be requested instead of
+	// Scopes by passing the name of the set in the ScopeSetParam query param of the button
+	// request. The name of the selected set is available in the button template as ScopeSet.
+	ScopeSets map[string][]string
+	// ScopeSetParam is the query param used to select one of the ScopeSets. Defaults to plan.
+	ScopeSetParam string
+	// ServeManifest enables the /.well-known/slack-app.json route, which describes the install
+	// endpoint with the client ID, the scopes and the authorize and redirect URLs as JSON.
+	ServeManifest bool
+	// EmitInstallJSON will write a JSON line to stdout for every successful install, with the
+	// team, the user, the granted scopes and the time of the install, but no tokens. These
+	// lines are written independently of the logs.
+	EmitInstallJSON bool
+	// ValidateButtonHost will check, every time the button template is read, that all the
+	// absolute links of the rendered template point to slack.com or to the host of the
+	// AuthorizeBaseURL, and fail otherwise.
+	ValidateButtonHost bool
+	// ReloadOnSIGHUP will reload the templates from disk every time the process receives a
+	// SIGHUP signal while the service is running.
+	ReloadOnSIGHUP bool
+	// AuthQueueSize is the number of auth events that can be waiting to be handled before
+	// new authorizations block. Defaults to 1.
+	AuthQueueSize int
+	// AccessLogFormat enables access logs for every request in the given format, which can be
+	// AccessLogStructured, AccessLogCommon or AccessLogCombined. Access logs are disabled if
+	// it's empty.
+	AccessLogFormat string
+	// AccessLogOutput is where common and combined access logs are written. Defaults to
+	// stdout.
+	AccessLogOutput io.Writer
+	// MaxScopeLength is the maximum length of the encoded scopes of the authorize URL, for
+	// both Scopes and ScopeSets. New fails if the scopes are longer. Defaults to
+	// DefaultMaxScopeLength.
+	MaxScopeLength int
+	// Resolver is the resolver used to look up the Slack API hosts, including the token
+	// exchange. If it's nil, the default resolver is used.
+	Resolver *net.Resolver
+	// RecentBufferSize is the number of recent auth events kept in memory and returned by
+	// RecentInstalls. Tokens, webhook URLs and the names and emails of the users are removed
+	// from them. Disabled if it's zero.
+	RecentBufferSize int
+	// ServeRecentInstalls enables the /debug/recent route, which returns the recent auth
+	// events as JSON. It requires RecentBufferSize. The route has no auth, so anyone who can
+	// reach the server can see the teams and user IDs of the recent installs.
+	ServeRecentInstalls bool
+	// StrictSlash makes routes match only without a trailing slash. By default, trailing
+	// slashes are ignored for the routes that match a single path, so /auth/ is handled like
+	// /auth, while the ButtonFS files and subtree patterns such as /hooks/ are left alone.
+	StrictSlash bool
+	// OptionalBot enables the /install route, which redirects users to the authorize URL
+	// requesting the bot scope only if the include_bot form value is true, so the button
+	// template can let users choose whether to add the bot, e.g. with a checkbox in a form
+	// pointing to InstallURL. The rest of the scopes are always requested.
+	OptionalBot bool
+	// DumpDir is a directory where every OAuth response will be written to its own JSON file,
+	// tokens included, so it can be inspected and fed again with Replay. It can only be used
+	// along with Debug.
+	DumpDir string
+	// ScopeDelimiter is the delimiter used to join the scopes in the authorize URL and in the
+	// templates. Defaults to DefaultScopeDelimiter.
+	ScopeDelimiter string
+	// ContextExtractor returns values from the button request, e.g. a campaign ID stored in a
+	// cookie, that will be available in the Context of the auth event. Since the button and
+	// the authorization are different requests, the values are encoded as JSON and kept in a
+	// cookie signed with the ParamSigner, which is required, for an hour or until the
+	// authorization is done.
+	ContextExtractor func(*http.Request) map[string]interface{}
+	// ButtonVariants are alternative button templates, by name, to compare how they convert.
+	// Every visitor is assigned at random one of them or ButtonTpl, named DefaultVariant, and
+	// keeps it in a cookie. The name of the variant is available in the button template and
+	// in the auth event as Variant.
+	ButtonVariants map[string]string
+	// Metrics receives the counters and durations of the service. If it's nil, metrics are
+	// discarded.
+	Metrics Metrics
+	// ErrorWebhookURL is the URL where a JSON object with the class and message of the error,
+	// the team, if known, and the time will be posted for every failed install. Tokens are
+	// redacted from the messages, and failed posts are retried a few times before being
+	// logged. Errors are posted one at a time, and dropped if too many are waiting.
+	ErrorWebhookURL string
+	// RequireAuthHandler makes Run fail with ErrNoAuthHandler unless an auth handler has been
+	// set with OnAuth, OnAuthEvent or OnAuthContext, or the Installs channel is enabled with
+	// InstallQueueSize.
+	RequireAuthHandler bool
+	// RateLimitTpl is the path to the template that will be displayed, with a 429 status, to
+	// the requests rejected by the rate limit. It receives RetryAfter, the number of seconds
+	// to wait before trying again. If it's not provided, the error template is used.
+	RateLimitTpl string
+	// OnCallbackPanic is the policy applied when an auth handler panics, either PanicRecover
+	// or PanicCrash. Defaults to PanicRecover, which keeps the service running at the cost of
+	// losing the event and possibly leaving the handler in a bad state. PanicCrash fails fast
+	// instead, so a supervisor can restart the process.
+	OnCallbackPanic string
+	// FeatureFlags returns the flags passed to the button template as Flags, so parts of the
+	// install page can be toggled at runtime. Flags not returned by it are false. It's called
+	// at most once every FeatureFlagsTTL, and the flags it returned are used for all the
+	// renders in between.
+	FeatureFlags func() map[string]bool
+	// FeatureFlagsTTL is the time the flags returned by FeatureFlags are cached. Defaults to
+	// 10 seconds.
+	FeatureFlagsTTL time.Duration
+	// CredentialsInHeader sends the client ID and client secret as basic auth during the
+	// exchange, instead of in the body of the request, for proxies that mangle one of the
+	// two forms. It has no effect on SlackAPIFallback.
+	CredentialsInHeader bool
+	// PlainTextForNonBrowsers responds to the authorizations that don't look like they come
+	// from a browser, because of their user agent or because they don't accept HTML, with a
+	// plain text "OK: <team>" line instead of the success template.
+	PlainTextForNonBrowsers bool
+	// EnrichTeamInfo calls team.info with the bot token after every exchange and makes the
+	// result available in the auth event and the success template as Team. Errors are only
+	// logged, and nothing is done if Slack did not return a bot token.
+	EnrichTeamInfo bool
+	// NormalizeTeamDomain lowercases the team domain returned by team.info and trims the
+	// spaces and dots around it before it's made available as TeamDomain. It has no effect
+	// unless EnrichTeamInfo is enabled, since the OAuth response has no domain.
+	NormalizeTeamDomain bool
+	// ListenConfig is used to create the listener of the server, so socket options such as
+	// SO_REUSEPORT can be set in its Control function. If it's nil, the default listener is
+	// used.
+	ListenConfig *net.ListenConfig
+	// ButtonAuthRedirect is the URL of a login page the users are redirected to, instead of
+	// seeing the gate page, when the OnBeforeButton handler returns false. The URL of the
+	// install page they were trying to see is added to it as the return_to param, as a path
+	// relative to the host of the service.
+	ButtonAuthRedirect string
+	// RateLimits are the maximum number of requests per second accepted by each route, keyed
+	// by route name, RouteButton or RouteAuth, in addition to RateLimit. Routes without a rate
+	// are not limited. All of them use RateLimitBurst.
+	RateLimits map[string]float64
+	// IdentityScopes are the Sign in with Slack scopes, such as identity.basic and
+	// identity.email, used to build the sign-in URL, which is available as SignInURL in the
+	// button template. They are kept apart from the install Scopes, and identity.basic must be
+	// one of them.
+	IdentityScopes []string
+	// MaxResponseBytes is the maximum size of a rendered page. Pages are rendered in full
+	// before being sent, and the ones over the limit are replaced with the error page, or a
+	// plain text message if it's the error page that fails. Defaults to
+	// DefaultMaxResponseBytes.
+	MaxResponseBytes int
+	// ExchangeParams are extra params, such as grant_type, added to the body of the exchange
+	// request, for Slack features the library does not support yet. They can't override the
+	// code, redirect_uri or credentials params. They have no effect on SlackAPIFallback.
+	ExchangeParams map[string]string
+	// ScopeSteps are the steps shown on the success page as Steps for each granted scope,
+	// keyed by scope. They replace DefaultScopeSteps, and a scope can be left out or mapped to
+	// an empty string to show no step for it.
+	ScopeSteps map[string]string
+	// StateTTL is the time a state token given to a user is valid. Every button render, and
+	// every redirect from the install and consent routes, sends a random state to Slack, kept
+	// in a cookie, and the authorization fails with a 400 unless Slack sends the same state
+	// back. The button template can use it as State, and AuthorizeURL includes it. Defaults to
+	// DefaultStateTTL. Negative values disable the check, which is needed if users start the
+	// authorization from the AuthorizeURL or UpgradeURL methods of the service.
+	StateTTL time.Duration
+	// ServeInstallStatus serves the status of the installs as server-sent events in
+	// /auth/status?state=<state>, so the button page can follow an install completed in
+	// another tab. Each event is a JSON object with the status, StatusInProgress,
+	// StatusSuccess or StatusError, and the error, if any. Streams are closed after a couple of
+	// seconds, and browsers reconnect to them. It requires the state check.
+	ServeInstallStatus bool
+	// OAuthVersion is the version of the Slack OAuth flow, OAuthV1 or OAuthV2. Defaults to
+	// OAuthV1. With OAuthV2, Scopes are the bot scopes, whose token is the bot access token of
+	// the response, and UserScopes are the scopes of the user token, which is its access
+	// token. With OAuthV1 there are no separate user scopes, and the bot token is only
+	// returned if Scopes include bot.
+	OAuthVersion string
+	// UserScopes are the scopes requested for the user token when using OAuthV2. They are
+	// sent as the user_scope param of the authorize URL.
+	UserScopes []string
+	// AppID is the ID of the Slack app, such as A0123456789. It's sent as the app_id param
+	// of the authorize URL, which some install link formats require, and is available as
+	// AppID in the button template. With OAuthV2, responses for other apps are rejected with
+	// ErrWrongApp.
+	AppID string
+	// SlowRequestThreshold logs a warning, with the duration, for every request and every
+	// exchange with Slack that takes longer than it. Exchange warnings include the team.
+	// Zero disables it.
+	SlowRequestThreshold time.Duration
+	// SuccessTplString is the success template itself, for templates that don't live in a
+	// file, such as the ones embedded in the binary. It can't be used along with SuccessTpl.
+	SuccessTplString string
+	// ErrorTplString is the error template itself. It can't be used along with ErrorTpl.
+	ErrorTplString string
+	// ButtonTplString is the button template itself. It can't be used along with ButtonTpl
+	// or ButtonFS.
+	ButtonTplString string
+	// ReinstallCooldown is the minimum time between two installs of the same team. Installs
+	// within it are rejected after the exchange, since the team is not known until then. The
+	// tokens are kept, since they may be the ones the team already uses. Only saved installs
+	// start the cooldown. Zero disables it.
+	ReinstallCooldown time.Duration
+	// CooldownStore keeps track of the installs for the ReinstallCooldown. Defaults to an
+	// in-memory store, which only works with a single instance.
+	CooldownStore CooldownStore
+	// CooldownTpl is the path to the template that will be displayed, with a 429 status, to
+	// the installs rejected by the ReinstallCooldown. It receives the same data as the error
+	// template. If it's not provided, the error template is used.
+	CooldownTpl string
 }
 
 // New creates a new slackauth service.
 func New(opts Options) (Service, error) {
-	if opts.Addr == "" || opts.ClientID == "" || opts.ClientSecret == "" {
+	opts.ClientID = strings.TrimSpace(opts.ClientID)
+	opts.ClientSecret = strings.TrimSpace(opts.ClientSecret)
+	opts.Addr = strings.TrimSpace(opts.Addr)
+	if (opts.Addr == "" && opts.UnixSocket == "") || (opts.CredentialsProvider == nil && (opts.ClientID == "" || opts.ClientSecret == "")) {
 		return nil, errors.New("slackauth: addr, client id and client secret can not be empty")
 	}
 
-	successTpl, err := readTemplate(opts.SuccessTpl)
+	if opts.Addr != "" && opts.UnixSocket != "" {
+		return nil, errors.New("slackauth: addr and unix socket can not be used together")
+	}
+
+	if opts.DumpDir != "" && !opts.Debug {
+		return nil, ErrDumpWithoutDebug
+	}
+
+	var clientCAs *x509.CertPool
+	if opts.RequireClientCert {
+		if opts.CertFile == "" || opts.KeyFile == "" {
+			return nil, errors.New("slackauth: client certificates can only be required with SSL")
+		}
+
+		var err error
+		if clientCAs, err = loadClientCAs(opts.ClientCAFile); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := validateIdempotencyKeyParam(opts); err != nil {
+		return nil, err
+	}
+
+	if opts.ContextExtractor != nil && opts.ParamSigner == nil {
+		return nil, ErrContextWithoutSigner
+	}
+
+	if err := validateOAuthVersion(opts.OAuthVersion); err != nil {
+		return nil, err
+	}
+
+	if err := validateAppID(opts.AppID); err != nil {
+		return nil, err
+	}
+
+	cfg, err := loadConfig(opts)
 	if err != nil {
 		return nil, err
 	}
 
-	errorTpl, err := readTemplate(opts.ErrorTpl)
+	hstsMaxAge := opts.HSTSMaxAge
+	if hstsMaxAge == 0 {
+		hstsMaxAge = defaultHSTSMaxAge
+	}
+
+	authorizeBaseURL := opts.AuthorizeBaseURL
+	if authorizeBaseURL == "" {
+		authorizeBaseURL = defaultAuthorizeBaseURL(opts.OAuthVersion)
+	}
+
+	stateTTL := opts.StateTTL
+	if stateTTL == 0 {
+		stateTTL = DefaultStateTTL
+	}
+
+	cooldowns := opts.CooldownStore
+	if cooldowns == nil && opts.ReinstallCooldown > 0 {
+		cooldowns = newMemoryCooldownStore()
+	}
+
+	var statuses *installStatuses
+	if opts.ServeInstallStatus {
+		if stateTTL < 0 {
+			return nil, ErrStatusWithoutState
+		}
+		statuses = newInstallStatuses(stateTTL)
+	}
+
+	queueSize := opts.AuthQueueSize
+	if queueSize <= 0 {
+		queueSize = 1
+	}
+
+	var limiter *rateLimiter
+	if opts.RateLimit > 0 {
+		limiter = newRateLimiter(opts.RateLimit, opts.RateLimitBurst)
+	}
+
+	routeLimiters, err := newRouteLimiters(opts.RateLimits, opts.RateLimitBurst)
 	if err != nil {
 		return nil, err
 	}
 
+	var installs chan *Install
+	if opts.InstallQueueSize > 0 {
+		installs = make(chan *Install, opts.InstallQueueSize)
+	}
+
+	maxScopeLength := opts.MaxScopeLength
+	if maxScopeLength <= 0 {
+		maxScopeLength = DefaultMaxScopeLength
+	}
+
+	if err := validateScopeLength(cfg.configuredScopes(), opts.ScopeDelimiter, maxScopeLength); err != nil {
+		return nil, err
+	}
+
+	if err := validateScopeSets(opts.ScopeSets, opts.ScopeDelimiter, maxScopeLength); err != nil {
+		return nil, err
+	}
+
+	scopeSetParam := opts.ScopeSetParam
+	if scopeSetParam == "" {
+		scopeSetParam = defaultScopeSetParam
+	}
+
+	var records *recordWriter
+	if opts.EmitInstallJSON {
+		records = &recordWriter{w: os.Stdout}
+	}
+
+	if err := validateAccessLogFormat(opts.AccessLogFormat); err != nil {
+		return nil, err
+	}
+
+	if err := validatePanicPolicy(opts.OnCallbackPanic); err != nil {
+		return nil, err
+	}
+
+	if err := validateIdentityScopes(opts.IdentityScopes); err != nil {
+		return nil, err
+	}
+
+	var buttonAuthRedirect *url.URL
+	if opts.ButtonAuthRedirect != "" {
+		buttonAuthRedirect, err = url.Parse(opts.ButtonAuthRedirect)
+		if err != nil {
+			return nil, fmt.Errorf("slackauth: invalid button auth redirect: %w", err)
+		}
+	}
+
+	var accessLogs *accessLogWriter
+	if opts.AccessLogFormat != "" {
+		out := opts.AccessLogOutput
+		if out == nil {
+			out = os.Stdout
+		}
+		accessLogs = &accessLogWriter{format: opts.AccessLogFormat, w: out}
+	}
+
+	api := &slackAPIWrapper{}
+	if opts.Resolver != nil {
+		api.client = newResolverClient(opts.Resolver)
+	}
+
+	exchangeClient := api.client
+	if opts.Debug {
+		exchangeClient = newDebugClient(exchangeClient)
+	}
+	api.exchangeClient = exchangeClient
+	api.credentialsInHeader = opts.CredentialsInHeader
+	api.exchangeParams = opts.ExchangeParams
+
+	var recent *recentInstalls
+	if opts.RecentBufferSize > 0 {
+		recent = newRecentInstalls(opts.RecentBufferSize)
+	}
+
+	var creds *credentialsCache
+	if opts.CredentialsProvider != nil {
+		creds = newCredentialsCache(opts.CredentialsProvider, opts.CredentialsTTL)
+	}
+
 	slackAuthService := &slackAuth{
 		clientID:     opts.ClientID,
 		clientSecret: opts.ClientSecret,
 		addr:         opts.Addr,
-		successTpl:   successTpl,
-		errorTpl:     errorTpl,
+		unixSocket:   opts.UnixSocket,
+		opts:         opts,
 		debug:        opts.Debug,
 		certFile:     opts.CertFile,
 		keyFile:      opts.KeyFile,
-		auths:        make(chan *slack.OAuthResponse, 1),
-		api:          &slackAPIWrapper{},
+		clientCAs:    clientCAs,
+		auths:        make(chan AuthEvent, queueSize),
+		api:          api,
+		fallbackAPI:  opts.SlackAPIFallback,
+
+		maintenanceFile: opts.MaintenanceFile,
+
+		notificationWebhook: opts.InstallNotificationWebhook,
+		postWebhook:         slack.PostWebhook,
+
+		idleTimeout: opts.IdleTimeout,
+		conns:       newConnStats(),
+		hstsMaxAge:  hstsMaxAge,
+
+		authorizeBaseURL: authorizeBaseURL,
+		store:            opts.TokenStore,
+		source:           opts.Source,
+		logSampleRate:    opts.LogSampleRate,
+		successSampling:  opts.SuccessLogSampleRate,
+		signer:           opts.ParamSigner,
+		signedParamNames: opts.SignedParams,
+		idempotencyParam: opts.IdempotencyKeyParam,
+		limiter:          limiter,
+		callbackTimeout:  opts.CallbackTimeout,
+		installs:         installs,
+		verifyToken:      opts.VerifyTokenOnInstall,
+
+		successRedirectURL:   opts.SuccessRedirectURL,
+		successRedirectDelay: opts.SuccessRedirectDelay,
+
+		creds: creds,
+
+		scopeSets:     opts.ScopeSets,
+		scopeSetParam: scopeSetParam,
+		serveManifest: opts.ServeManifest,
+		records:       records,
+
+		reloadOnSIGHUP: opts.ReloadOnSIGHUP,
+		accessLogs:     accessLogs,
+
+		slowRequestThreshold: opts.SlowRequestThreshold,
+
+		recent:      recent,
+		serveRecent: opts.ServeRecentInstalls && recent != nil,
+		strictSlash: opts.StrictSlash,
+		optionalBot: opts.OptionalBot,
+		dumpDir:     opts.DumpDir,
+		consent:     opts.ConsentTpl != "",
+
+		scopeDelimiter: opts.ScopeDelimiter,
+
+		contextExtractor: opts.ContextExtractor,
+		metricsSink:      opts.Metrics,
+
+		errorWebhook: opts.ErrorWebhookURL,
+
+		requireAuthHandler: opts.RequireAuthHandler,
+
+		panicPolicy: opts.OnCallbackPanic,
+
+		plainTextForNonBrowsers: opts.PlainTextForNonBrowsers,
+		enrichTeamInfo:          opts.EnrichTeamInfo,
+		normalizeTeamDomain:     opts.NormalizeTeamDomain,
+
+		listenConfig: opts.ListenConfig,
+
+		buttonAuthRedirect: buttonAuthRedirect,
+
+		routeLimiters: routeLimiters,
+
+		identityScopes: opts.IdentityScopes,
+
+		stepDefs: opts.ScopeSteps,
+		stateTTL: stateTTL,
+
+		statuses: statuses,
+
+		oauthVersion: opts.OAuthVersion,
+		userScopes:   opts.UserScopes,
+
+		appID: opts.AppID,
+
+		reinstallCooldown: opts.ReinstallCooldown,
+		cooldowns:         cooldowns,
 	}
 
-	err = slackAuthService.configureButton(opts.ButtonTpl, opts.Scopes)
-	if err != nil {
-		return nil, err
+	if opts.FeatureFlags != nil {
+		slackAuthService.flags = newFeatureFlagsCache(opts.FeatureFlags, opts.FeatureFlagsTTL)
 	}
+
+	if opts.Debug {
+		slackAuthService.debugLogHeaders = opts.DebugLogHeaders
+	}
+
+	if opts.ButtonFS != nil {
+		slackAuthService.buttonFS = opts.ButtonFS
+		slackAuthService.buttonIndex = buttonIndexPath(opts.ButtonIndex)
+	}
+
+	slackAuthService.setConfig(cfg)
 	return slackAuthService, nil
 }
 
-func (s *slackAuth) configureButton(buttonTpl string, scopes []string) error {
-	if len(buttonTpl) > 0 {
-		buttonTpl, err := readTemplate(buttonTpl)
-		if err != nil {
-			return err
-		}
+func (s *slackAuth) Run() error {
+	if s.requireAuthHandler && !s.hasAuthHandler() {
+		return ErrNoAuthHandler
+	}
 
-		if len(scopes) == 0 {
-			return errors.New("At least one scope needed")
-		}
+	if err := s.preflight(); err != nil {
+		return err
+	}
+
+	s.startConsumer()
 
-		s.scopes = strings.Join(scopes, ",")
-		s.buttonTpl = buttonTpl
+	if s.reloadOnSIGHUP {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGHUP)
+		defer func() {
+			signal.Stop(signals)
+			close(signals)
+		}()
+		go s.reloadOnSignal(signals)
 	}
 
+	log15.Info("Starting server", "addr", s.addr)
+	if err := s.runServer(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
 	return nil
 }
 
-func (s *slackAuth) Run() error {
-	go func() {
-		for auth := range s.auths {
-			if s.callback != nil {
-				s.callback(auth)
-			} else {
-				log15.Warn("auth event triggered but there was no handler")
-			}
-		}
-	}()
-
-	log15.Info("Starting server", "addr", s.addr)
-	return s.runServer()
+func (s *slackAuth) Handler() http.Handler {
+	s.startConsumer()
+	return s.handler()
 }
 
 func (s *slackAuth) SetLogOutput(w io.Writer) {
@@ -172,70 +1100,677 @@ func (s *slackAuth) SetLogOutput(w io.Writer) {
 		w = os.Stdout
 		format = log15.TerminalFormat()
 	}
+	s.logOut.swap(w)
 
 	var maxLvl = log15.LvlInfo
 	if s.debug {
 		maxLvl = log15.LvlDebug
 	}
 
-	log15.Root().SetHandler(log15.LvlFilterHandler(maxLvl, log15.StreamHandler(w, format)))
+	log15.Root().SetHandler(log15.LvlFilterHandler(maxLvl, log15.StreamHandler(&s.logOut, format)))
 }
 
 func (s *slackAuth) OnAuth(fn func(*slack.OAuthResponse)) {
+	s.handlersMut.Lock()
 	s.callback = fn
+	s.handlersMut.Unlock()
 }
 
-func (s *slackAuth) runServer() error {
+func (s *slackAuth) OnAuthEvent(fn func(AuthEvent)) {
+	s.handlersMut.Lock()
+	s.eventHandler = fn
+	s.handlersMut.Unlock()
+}
+
+func (s *slackAuth) OnAuthContext(fn func(context.Context, AuthEvent) error) {
+	s.handlersMut.Lock()
+	s.ctxHandler = fn
+	s.handlersMut.Unlock()
+}
+
+func (s *slackAuth) OnAuthV2(fn func(*OAuthV2Response)) {
+	s.handlersMut.Lock()
+	s.v2Handler = fn
+	s.handlersMut.Unlock()
+}
+
+func (s *slackAuth) OnError(fn func(error, *http.Request)) {
+	s.handlersMut.Lock()
+	s.errorHandler = fn
+	s.handlersMut.Unlock()
+}
+
+// handleError triggers the error handler, if any, with the given error and request.
+func (s *slackAuth) handleError(err error, r *http.Request) {
+	s.handlersMut.RLock()
+	handler := s.errorHandler
+	s.handlersMut.RUnlock()
+
+	if handler != nil {
+		handler(err, r)
+	}
+}
+
+// ErrNoAuthHandler is returned by Run when RequireAuthHandler is set and there is no way for
+// auth events to be handled.
+var ErrNoAuthHandler = errors.New("slackauth: no auth handler registered")
+
+// hasAuthHandler reports whether auth events will be handled by a handler or a consumer of
+// the Installs channel.
+func (s *slackAuth) hasAuthHandler() bool {
+	s.handlersMut.RLock()
+	defer s.handlersMut.RUnlock()
+	return s.callback != nil || s.eventHandler != nil || s.ctxHandler != nil || s.v2Handler != nil || s.installs != nil
+}
+
+// dispatch triggers the auth handlers with the given event. Handlers can be set at any time,
+// even while events are being dispatched.
+func (s *slackAuth) dispatch(event AuthEvent) {
+	s.handlersMut.RLock()
+	callback, eventHandler, ctxHandler, v2Handler := s.callback, s.eventHandler, s.ctxHandler, s.v2Handler
+	s.handlersMut.RUnlock()
+
+	if callback == nil && eventHandler == nil && ctxHandler == nil && v2Handler == nil {
+		log15.Warn("auth event triggered but there was no handler")
+		return
+	}
+
+	if callback != nil {
+		callback(event.Response)
+	}
+
+	if v2Handler != nil && event.ResponseV2 != nil {
+		v2Handler(event.ResponseV2)
+	}
+
+	if eventHandler != nil {
+		eventHandler(event)
+	}
+
+	if ctxHandler != nil {
+		s.runCtxHandler(ctxHandler, event)
+	}
+}
+
+// runCtxHandler runs the given context handler with the event, waiting at most the callback
+// timeout for it to finish. Errors and timeouts go to the error handler.
+func (s *slackAuth) runCtxHandler(handler func(context.Context, AuthEvent) error, event AuthEvent) {
+	ctx, cancel := context.Background(), func() {}
+	if s.callbackTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, s.callbackTimeout)
+	}
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		defer close(done)
+		defer s.recoverCallback()
+		done <- handler(ctx, event)
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			log15.Error("error handling auth event", "step", "callback", "err", err.Error())
+			s.handleError(err, nil)
+		}
+	case <-ctx.Done():
+		log15.Error("timeout handling auth event", "step", "callback", "err", ctx.Err().Error())
+		s.handleError(ctx.Err(), nil)
+	}
+}
+
+func (s *slackAuth) HandleCallback(ctx context.Context, code string) (*slack.OAuthResponse, error) {
+	event, err := s.exchange(ctx, code, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	s.dispatchRecovering(event)
+	s.recordRecent(event)
+	s.countGrantedScopes(event.Response)
+	s.deliverInstall(ctx, event)
+	return event.Response, nil
+}
+
+// errAuthorizationDenied is reported to the error webhook instead of the error sent by Slack
+// when the authorization is denied, which can be set by anyone.
+var errAuthorizationDenied = errors.New("authorization denied")
+
+// ErrEmptyOAuthResponse is returned when the Slack API returns neither an OAuth response nor
+// an error for the exchange.
+var ErrEmptyOAuthResponse = errors.New("slackauth: slack returned an empty oauth response")
+
+// exchange exchanges the given authorization code for an OAuth response and returns the
+// auth event for it. If requested is not nil, the exchange fails when Slack granted scopes
+// that are not in it.
+func (s *slackAuth) exchange(ctx context.Context, code string, requested []string) (AuthEvent, error) {
+	clientID, clientSecret, err := s.credentials()
+	if err != nil {
+		log15.Error("error getting credentials", "step", "get_credentials", "err", err.Error())
+		return AuthEvent{}, err
+	}
+
+	// api is the client that performed the exchange, which also verifies the token.
+	api, path := s.api, "primary"
+	start := time.Now()
+	resp, respV2, err := s.oauthResponse(ctx, api, clientID, clientSecret, code)
+	elapsed := time.Since(start)
+	s.metrics().ObserveDuration(MetricExchangeDuration, elapsed, "api:primary")
+	if err != nil && s.fallbackAPI != nil && isTransient(err) {
+		log15.Warn("error getting oauth response, retrying with fallback", "step", "slack_exchange", "err", err.Error())
+		api, path = s.fallbackAPI, "fallback"
+		start = time.Now()
+		resp, respV2, err = s.oauthResponse(ctx, api, clientID, clientSecret, code)
+		elapsed = time.Since(start)
+		s.metrics().ObserveDuration(MetricExchangeDuration, elapsed, "api:fallback")
+	}
+
+	if err != nil {
+		return AuthEvent{}, err
+	}
+	if resp == nil {
+		return AuthEvent{}, ErrEmptyOAuthResponse
+	}
+	if err := s.checkAppID(respV2); err != nil {
+		log15.Error("oauth response for another app", "step", "check_app_id", "team id", resp.TeamID, "err", err.Error())
+		return AuthEvent{}, withTeam(resp.TeamID, err)
+	}
+	s.warnSlowExchange(path, resp, elapsed)
+
+	if sampledAt(s.successSampling) {
+		log15.Debug("successful authorization", "step", "slack_exchange", "path", path, "team", resp.TeamName, "team id", resp.TeamID)
+	}
+	if s.verifyToken {
+		identity, err := api.AuthTest(ctx, resp.AccessToken)
+		if err != nil {
+			log15.Error("error verifying access token", "step", "verify_token", "team id", resp.TeamID, "err", err.Error())
+			return AuthEvent{}, withTeam(resp.TeamID, err)
+		}
+		log15.Debug("verified access token", "step", "verify_token", "team", identity.Team, "user", identity.User, "user id", identity.UserID)
+	}
+
+	if requested != nil {
+		// Users who signed in instead of installing the app come back with the identity
+		// scopes, whatever the button requested.
+		allowed := append(append([]string(nil), requested...), s.identityScopes...)
+		if s.oauthVersion == OAuthV2 {
+			allowed = append(allowed, s.userScopes...)
+		}
+		if err := checkGrantedScopes(grantedScopes(resp), allowed); err != nil {
+			log15.Error("unexpected granted scopes", "step", "check_scopes", "team id", resp.TeamID, "err", err.Error())
+			return AuthEvent{}, withTeam(resp.TeamID, err)
+		}
+	}
+
+	if err := s.checkCooldown(ctx, resp); err != nil {
+		return AuthEvent{}, withTeam(resp.TeamID, err)
+	}
+
+	reinstall := s.isReinstall(ctx, resp)
+	if s.store != nil && s.installs == nil {
+		if err := s.store.Save(ctx, resp); err != nil {
+			log15.Error("error saving oauth response", "step", "save_token", "err", err.Error())
+			return AuthEvent{}, withTeam(resp.TeamID, err)
+		}
+	}
+	s.recordCooldown(ctx, resp)
+
+	if s.notificationWebhook != "" {
+		go s.notifyInstall(resp)
+	}
+
+	if s.records != nil {
+		s.records.write(resp)
+	}
+
+	if s.dumpDir != "" {
+		s.dump(resp)
+	}
+
+	team := s.teamInfo(ctx, resp)
+	return AuthEvent{
+		Response:   resp,
+		ResponseV2: respV2,
+		Source:     s.source,
+		Identity:   s.identity(ctx, resp),
+		Reinstall:  reinstall,
+		Team:       team,
+		TeamDomain: s.teamDomain(team),
+	}, nil
+}
+
+func (s *slackAuth) handler() http.Handler {
 	mux := http.NewServeMux()
-	mux.HandleFunc("/", s.buttonHandler)
-	mux.HandleFunc("/auth", s.authorizationHandler)
+	mux.HandleFunc("/", allow(s.limitRoute(RouteButton, s.maintenance(s.buttonHandler)), http.MethodGet, http.MethodHead))
+	mux.HandleFunc("/auth", allow(s.limitRoute(RouteAuth, s.maintenance(s.authorizationHandler)), http.MethodGet))
+	if s.serveManifest {
+		mux.HandleFunc(manifestPath, allow(s.manifestHandler, http.MethodGet))
+	}
+	if s.serveRecent {
+		mux.HandleFunc(recentPath, allow(s.recentHandler, http.MethodGet))
+	}
+	if s.optionalBot {
+		mux.HandleFunc(installPath, allow(s.maintenance(s.installHandler), http.MethodGet))
+	}
+	if s.consent {
+		mux.HandleFunc(consentPath, allow(s.maintenance(s.consentHandler), http.MethodPost))
+	}
+	if s.statuses != nil {
+		mux.HandleFunc(statusPath, allow(s.statusHandler, http.MethodGet))
+	}
+	s.handleRoutes(mux)
+	return s.accessLog(s.secureHeaders(s.rateLimit(s.trimSlash(mux))))
+}
+
+func (s *slackAuth) useTLS() bool {
+	return s.certFile != "" && s.keyFile != ""
+}
 
+// secureHeaders adds security related headers to all responses. Strict-Transport-Security is
+// only sent when the server runs with SSL.
+func (s *slackAuth) secureHeaders(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		if s.useTLS() && s.hstsMaxAge > 0 {
+			w.Header().Set("Strict-Transport-Security", fmt.Sprintf("max-age=%d", int64(s.hstsMaxAge/time.Second)))
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+func (s *slackAuth) runServer() error {
 	srv := &http.Server{
 		ReadTimeout:  1 * time.Second,
 		WriteTimeout: 3 * time.Second,
+		IdleTimeout:  s.idleTimeout,
 		Addr:         s.addr,
-		Handler:      mux,
+		Handler:      s.handler(),
+		ConnState:    s.conns.track,
+		TLSConfig:    s.tlsConfig(),
+	}
+	if !s.startServer(srv) {
+		return http.ErrServerClosed
 	}
 
-	if s.certFile != "" && s.keyFile != "" {
+	if s.unixSocket != "" || s.listenConfig != nil {
+		ln, err := s.listen()
+		if err != nil {
+			return err
+		}
+		defer ln.Close()
+
+		if s.useTLS() {
+			return srv.ServeTLS(ln, s.certFile, s.keyFile)
+		}
+		return srv.Serve(ln)
+	}
+
+	if s.useTLS() {
 		return srv.ListenAndServeTLS(s.certFile, s.keyFile)
 	}
 
 	return srv.ListenAndServe()
 }
 
+// maintenance wraps an install route so it displays the maintenance page while the
+// maintenance file exists. The file is checked on every request, so maintenance mode can
+// be toggled without restarting the service.
+func (s *slackAuth) maintenance(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.inMaintenance() {
+			h(w, r)
+			return
+		}
+
+		cfg := s.config()
+		if cfg.maintenanceTpl != nil {
+			err := cfg.render(w, http.StatusServiceUnavailable, "", cfg.maintenanceTpl, nil)
+			if err == nil {
+				return
+			}
+			log15.Error("error displaying maintenance tpl", "step", "render_maintenance", "err", err.Error())
+		}
+
+		w.WriteHeader(http.StatusServiceUnavailable)
+		io.WriteString(w, "Service under maintenance, please try again later.")
+	}
+}
+
+func (s *slackAuth) inMaintenance() bool {
+	if s.maintenanceFile == "" {
+		return false
+	}
+
+	_, err := os.Stat(s.maintenanceFile)
+	return err == nil
+}
+
 func (s *slackAuth) authorizationHandler(w http.ResponseWriter, r *http.Request) {
-	code := r.FormValue("code")
-	resp, err := s.api.GetOAuthResponse(s.clientID, s.clientSecret, code, s.debug)
+	cfg := s.config()
+	if s.isStopped() {
+		cfg.renderError(w, http.StatusServiceUnavailable, "the service is stopped")
+		return
+	}
+
+	s.logDebugHeaders(r)
+	if err := r.ParseForm(); err != nil {
+		log15.Error("error parsing form", "step", "parse_form", "err", err.Error())
+		cfg.renderError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+
+	params, err := s.verifyParams(r)
 	if err != nil {
-		w.WriteHeader(http.StatusUnauthorized)
-		log15.Error("error getting oauth response", "err", err.Error())
-		if err := s.errorTpl.Execute(w, resp); err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			log15.Error("error displaying error tpl", "err", err.Error())
+		log15.Error("error verifying signed params", "step", "verify_params", "err", err.Error())
+		s.reportError("verify_params", "", err)
+		cfg.renderError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	requested, err := s.verifiedScopes(r)
+	if err != nil {
+		log15.Error("error verifying requested scopes", "step", "verify_scopes", "err", err.Error())
+		s.reportError("verify_scopes", "", err)
+		cfg.renderError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	code := r.FormValue("code")
+	if code == "" {
+		if slackErr := r.FormValue("error"); slackErr != "" {
+			s.metrics().IncrCounter(MetricInstallErrors, "step:authorization_denied")
+			log15.Error("authorization denied", "step", "parse_form", "err", slackErr)
+			err := errors.New(slackErr)
+			// The error comes from the request, so it's not sent to the webhook as is.
+			s.reportError("authorization_denied", "", errAuthorizationDenied)
+			s.handleError(err, r)
+			cfg.renderError(w, http.StatusUnauthorized, slackErr)
+		} else {
+			log15.Error("missing authorization code", "step", "parse_form")
+			cfg.renderError(w, http.StatusBadRequest, "missing authorization code")
 		}
+		return
+	}
 
+	if err := s.verifyState(r); err != nil {
+		log15.Error("error verifying state", "step", "verify_state", "err", err.Error())
+		s.reportError("verify_state", "", err)
+		cfg.renderError(w, http.StatusBadRequest, err.Error())
 		return
 	}
+	if s.stateTTL > 0 {
+		clearCookie(w, stateCookie)
+	}
 
-	if err := s.successTpl.Execute(w, resp); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		log15.Error("error displaying success tpl", "err", err.Error())
+	state := r.FormValue("state")
+	s.setStatus(state, StatusInProgress, nil)
+	event, err := s.exchange(r.Context(), code, requested)
+	if errors.Is(err, ErrReinstallCooldown) {
+		s.metrics().IncrCounter(MetricInstallErrors, "step:reinstall_cooldown")
+		log15.Error("reinstall rejected by the cooldown", "step", "reinstall_cooldown", "err", err.Error())
+		s.reportError("reinstall_cooldown", errorTeamID(err), err)
+		s.handleError(err, r)
+		s.setStatus(state, StatusError, err)
+		cfg.renderCooldown(w)
+		return
+	}
+	if err != nil {
+		s.metrics().IncrCounter(MetricInstallErrors, "step:slack_exchange")
+		log15.Error("error getting oauth response", "step", "slack_exchange", "err", err.Error())
+		s.reportError("slack_exchange", errorTeamID(err), err)
+		s.handleError(err, r)
+		s.setStatus(state, StatusError, err)
+		cfg.renderError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+	defer s.setStatus(state, StatusSuccess, nil)
+
+	if requested != nil {
+		clearCookie(w, scopesCookie)
+	}
+
+	event.Variant = requestVariant(r, cfg)
+	if event.Context = s.buttonContext(r); event.Context != nil {
+		clearCookie(w, contextCookie)
+	}
+
+	if s.handlePartialScope(cfg, event, requested, w, r) {
+		s.completeAuthorization(event, r, params)
+		return
+	}
+
+	if s.plainTextForNonBrowsers && !isBrowser(r) {
+		writePlainTextSuccess(w, s.newSuccessData(event))
+		s.completeAuthorization(event, r, params)
+		return
+	}
+
+	if s.successRedirectURL != "" {
+		w.Header().Set("Refresh", fmt.Sprintf("%d; url=%s", int64(s.successRedirectDelay/time.Second), s.successRedirectURL))
 	}
 
-	log15.Debug("successful authorization", "team", resp.TeamName, "team id", resp.TeamID)
-	s.auths <- resp
+	tpl := cfg.successTpl
+	if event.Reinstall && cfg.reinstallTpl != nil {
+		tpl = cfg.reinstallTpl
+	}
+
+	if err := cfg.render(w, http.StatusOK, cfg.successContentType, tpl, s.newSuccessData(event)); err != nil {
+		log15.Error("error displaying success tpl", "step", "render_success", "err", err.Error())
+		s.handleError(err, r)
+		cfg.renderError(w, http.StatusInternalServerError, "the app was installed, but the page could not be displayed")
+	}
+
+	s.completeAuthorization(event, r, params)
+}
+
+// completeAuthorization sends the auth event of a successful authorization request.
+func (s *slackAuth) completeAuthorization(event AuthEvent, r *http.Request, params url.Values) {
+	s.metrics().IncrCounter(MetricInstalls)
+	event.Request, event.Params = r, params
+	if s.idempotencyParam != "" {
+		event.IdempotencyKey = params.Get(s.idempotencyParam)
+	}
+	if event.Source == "" {
+		event.Source = r.URL.Path
+	}
+
+	if !s.sendAuth(event) {
+		log15.Warn("auth event dropped, the service is stopped", "step", "dispatch", "team id", event.Response.TeamID)
+		return
+	}
+	s.trackAuthQueue()
+	s.recordRecent(event)
+	s.countGrantedScopes(event.Response)
+	s.deliverInstall(r.Context(), event)
+}
+
+// notifyInstall posts a message about the given install to the install notification
+// webhook. Failures are logged and go to the error handler, they never affect the user
+// installing the app.
+func (s *slackAuth) notifyInstall(resp *slack.OAuthResponse) {
+	msg := &slack.WebhookMessage{
+		Text: fmt.Sprintf(
+			"New install on team *%s* (%s) by <@%s> with scopes: %s",
+			resp.TeamName, resp.TeamID, resp.UserID, resp.Scope,
+		),
+	}
+
+	if err := s.postWebhook(s.notificationWebhook, msg); err != nil {
+		log15.Error("error posting install notification", "step", "notify_install", "err", err.Error())
+		s.handleError(err, nil)
+	}
+}
+
+// successData is the data the success template is rendered with. All the fields of the
+// OAuth response are available in the template along with the extra ones defined here.
+type successData struct {
+	*slack.OAuthResponse
+	// DisplayName is the name of the team, or its ID if Slack did not return the name.
+	DisplayName string
+	// RedirectURL is the URL the user will be redirected to after RedirectDelay seconds, if any.
+	RedirectURL string
+	// RedirectDelay is the number of seconds before the user is redirected to RedirectURL.
+	RedirectDelay int64
+	// Email is the email of the user who authorized the app, if the identity.email scope was
+	// granted.
+	Email string
+	// RealName is the name of the user who authorized the app, if identity scopes were granted.
+	RealName string
+	// Team is the info of the team, with its domain and icon, if EnrichTeamInfo is enabled.
+	Team *slack.TeamInfo
+	// TeamDomain is the domain of the team, normalized if NormalizeTeamDomain is enabled.
+	TeamDomain string
+	// Steps are the things the user can do next, according to the granted scopes.
+	Steps []Step
+}
+
+func (s *slackAuth) newSuccessData(event AuthEvent) successData {
+	resp := event.Response
+	displayName := resp.TeamName
+	if displayName == "" {
+		displayName = resp.TeamID
+	}
+
+	return successData{
+		OAuthResponse: resp,
+		DisplayName:   displayName,
+		RedirectURL:   s.successRedirectURL,
+		RedirectDelay: int64(s.successRedirectDelay / time.Second),
+		Email:         event.Identity.Email,
+		RealName:      event.Identity.RealName,
+		Team:          event.Team,
+		TeamDomain:    event.TeamDomain,
+		Steps:         s.scopeSteps(grantedScopes(resp)),
+	}
+}
+
+// errorData is the data the error template is rendered with.
+type errorData struct {
+	// Error is a description of what went wrong.
+	Error string
+}
+
+func (c *config) renderError(w http.ResponseWriter, status int, msg string) {
+	if err := c.render(w, status, c.errorContentType, c.errorTpl, errorData{Error: msg}); err != nil {
+		log15.Error("error displaying error tpl", "step", "render_error", "err", err.Error())
+		http.Error(w, msg, status)
+	}
+}
+
+// sampled reports whether a high-volume log line should be written according to the log
+// sample rate.
+func (s *slackAuth) sampled() bool {
+	return sampledAt(s.logSampleRate)
+}
+
+// sampledAt reports whether a log line sampled at the given rate should be written.
+func sampledAt(rate float64) bool {
+	if rate <= 0 || rate >= 1 {
+		return true
+	}
+	return rand.Float64() < rate
 }
 
 func (s *slackAuth) buttonHandler(w http.ResponseWriter, r *http.Request) {
-	templateScope := map[string]string{
-		"Scopes":   s.scopes,
-		"ClientId": s.clientID,
+	if s.serveButtonAsset(w, r) {
+		return
+	}
+
+	cfg := s.config()
+	if cfg.buttonTpl == nil && cfg.consentTpl == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	if s.gateButton(w, r) {
+		return
+	}
+
+	// Probes only need the headers, so the template is not rendered for them.
+	if r.Method == http.MethodHead {
+		setContentType(w, cfg.buttonContentType)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if s.sampled() {
+		log15.Debug("button view", "step", "render_button", "path", r.URL.Path, "user agent", r.UserAgent())
+	}
+
+	signedParams, err := s.signParams(r)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		log15.Error("error signing params", "step", "sign_params", "err", err.Error())
+		return
+	}
+
+	scopes, scopeSet, err := s.requestedScopes(cfg, r)
+	if err != nil {
+		log15.Error("error selecting scopes", "step", "select_scopes", "err", err.Error())
+		cfg.renderError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	authorizeURL := s.configuredAuthorizeURL(cfg)
+	if scopeSet != "" {
+		authorizeURL = s.authorizeURL(scopes)
 	}
-	if err := s.buttonTpl.Execute(w, templateScope); err != nil {
+
+	if err := s.setScopesCookie(w, r, scopes); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		log15.Error("error signing scopes", "step", "sign_scopes", "err", err.Error())
+		return
+	}
+
+	if err := s.setButtonContext(w, r); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		log15.Error("error storing button context", "step", "button_context", "err", err.Error())
+		return
+	}
+
+	state, err := s.setState(w, r)
+	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
-		log15.Error("error displaying button tpl", "err", err.Error())
+		log15.Error("error generating state", "step", "generate_state", "err", err.Error())
+		return
+	}
+	authorizeURL = withState(authorizeURL, state)
+
+	templateScope := map[string]interface{}{
+		"Scopes":       s.joinScopes(scopes),
+		"ScopeSet":     scopeSet,
+		"ClientId":     s.currentClientID(),
+		"AuthorizeURL": authorizeURL,
+		"SignedParams": signedParams,
+		"Flags":        s.featureFlags(),
+		"State":        state,
+	}
+	if s.optionalBot {
+		templateScope["InstallURL"] = installPath
+	}
+	if s.appID != "" {
+		templateScope["AppID"] = s.appID
+	}
+	if len(s.identityScopes) > 0 {
+		templateScope["SignInURL"] = s.SignInURL()
+	}
+
+	variant := s.buttonVariant(w, r, cfg)
+	templateScope["Variant"] = variant
+
+	tpl := cfg.variantTemplate(variant)
+	if cfg.consentTpl != nil {
+		tpl = cfg.consentTpl
+		templateScope["ConsentURL"] = consentPath
+	}
+
+	if err := cfg.render(w, http.StatusOK, cfg.buttonContentType, tpl, templateScope); err != nil {
+		log15.Error("error displaying button tpl", "step", "render_button", "err", err.Error())
+		cfg.renderError(w, http.StatusInternalServerError, "the install page could not be displayed")
+		return
 	}
+	s.metrics().IncrCounter(MetricButtonViews)
 }
 
 func readTemplate(file string) (*template.Template, error) {
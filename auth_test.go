@@ -1,25 +1,30 @@
 package slackauth
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"html/template"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"os"
 	"regexp"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/nlopes/slack"
 	"github.com/stretchr/testify/assert"
+	log15 "gopkg.in/inconshreveable/log15.v2"
 )
 
 type slackAPIMock struct{}
 
-func (*slackAPIMock) GetOAuthResponse(id, secret, code string, debug bool) (*slack.OAuthResponse, error) {
+func (*slackAPIMock) GetOAuthResponse(ctx context.Context, id, secret, code string, debug bool) (*slack.OAuthResponse, error) {
 	if code == "invalid" {
 		return nil, errors.New("invalid code")
 	}
@@ -29,6 +34,47 @@ func (*slackAPIMock) GetOAuthResponse(id, secret, code string, debug bool) (*sla
 	}, nil
 }
 
+func (*slackAPIMock) GetOAuthV2Response(ctx context.Context, id, secret, code string, debug bool) (*OAuthV2Response, error) {
+	if code == "invalid" {
+		return nil, errors.New("invalid code")
+	}
+
+	return &OAuthV2Response{
+		AccessToken: "xoxb-1",
+		Scope:       "chat:write",
+		Team:        OAuthV2Team{ID: "T1", Name: "Acme"},
+		AuthedUser:  OAuthV2AuthedUser{ID: "U1", Scope: "identity.basic", AccessToken: "foo"},
+	}, nil
+}
+
+func (*slackAPIMock) AuthTest(ctx context.Context, token string) (*slack.AuthTestResponse, error) {
+	if token != "foo" {
+		return nil, errors.New("invalid_auth")
+	}
+
+	return &slack.AuthTestResponse{User: "bar"}, nil
+}
+
+func (*slackAPIMock) GetUserIdentity(ctx context.Context, token string) (*slack.UserIdentityResponse, error) {
+	if token != "foo" {
+		return nil, errors.New("invalid_auth")
+	}
+
+	resp := &slack.UserIdentityResponse{}
+	resp.User.ID = "U1"
+	resp.User.Name = "Jane Doe"
+	resp.User.Email = "jane@example.com"
+	return resp, nil
+}
+
+func (*slackAPIMock) GetTeamInfo(ctx context.Context, token string) (*slack.TeamInfo, error) {
+	if token != "xoxb-1" {
+		return nil, errors.New("invalid_auth")
+	}
+
+	return &slack.TeamInfo{ID: "T1", Domain: "acme"}, nil
+}
+
 const (
 	tplSuccess = `<h1>Hello</h1>
 	<p>All went ok!</p>`
@@ -53,6 +99,27 @@ func TestNew(t *testing.T) {
 	}{
 		{Options{}, true},
 		{Options{Addr: "", ClientID: "a", ClientSecret: "b"}, true},
+		{Options{
+			Addr:         "  ",
+			ClientID:     "foo",
+			ClientSecret: "bar",
+			SuccessTpl:   "valid.txt",
+			ErrorTpl:     "valid.txt",
+		}, true},
+		{Options{
+			Addr:         ":8080",
+			ClientID:     " ",
+			ClientSecret: "bar",
+			SuccessTpl:   "valid.txt",
+			ErrorTpl:     "valid.txt",
+		}, true},
+		{Options{
+			Addr:         ":8080",
+			ClientID:     "foo",
+			ClientSecret: "\t",
+			SuccessTpl:   "valid.txt",
+			ErrorTpl:     "valid.txt",
+		}, true},
 		{Options{
 			Addr:         ":8080",
 			ClientID:     "foo",
@@ -125,18 +192,20 @@ func TestNew(t *testing.T) {
 func TestSlackAuth(t *testing.T) {
 	successTpl := template.Must(template.New("success").Parse(tplSuccess))
 	errorTpl := template.Must(template.New("error").Parse(tplError))
-	auth := &slackAuth{
+	auth := withConfig(&slackAuth{
 		clientID:     "aaaa",
 		clientSecret: "bbbb",
 		addr:         ":8989",
-		successTpl:   successTpl,
-		errorTpl:     errorTpl,
 		debug:        true,
 		certFile:     "",
 		keyFile:      "",
-		auths:        make(chan *slack.OAuthResponse, 1),
+		auths:        make(chan AuthEvent, 1),
 		api:          &slackAPIMock{},
-	}
+		conns:        newConnStats(),
+	}, &config{
+		successTpl: successTpl,
+		errorTpl:   errorTpl,
+	})
 	auth.SetLogOutput(os.Stdout)
 	go auth.Run()
 
@@ -146,13 +215,14 @@ func TestSlackAuth(t *testing.T) {
 	testRequest(t, getURLForAuth("fooo"), tplSuccess)
 	testRequest(t, getURLForAuth("invalid"), tplError)
 
-	var auths int
+	var auths int32
 	auth.OnAuth(func(auth *slack.OAuthResponse) {
-		auths++
+		atomic.AddInt32(&auths, 1)
 	})
 	testRequest(t, getURLForAuth("fooo"), tplSuccess)
 	testRequest(t, getURLForAuth("bar"), tplSuccess)
-	assert.Equal(t, 2, auths)
+	<-time.After(50 * time.Millisecond)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&auths))
 }
 
 func getURLForAuth(code string) string {
@@ -208,3 +278,538 @@ func TestSlackButton(t *testing.T) {
 
 	assert.Nil(t, os.Remove("valid.txt"))
 }
+
+func TestMaintenance(t *testing.T) {
+	successTpl := template.Must(template.New("success").Parse(tplSuccess))
+	errorTpl := template.Must(template.New("error").Parse(tplError))
+	auth := withConfig(&slackAuth{
+		clientID:        "aaaa",
+		clientSecret:    "bbbb",
+		auths:           make(chan AuthEvent, 1),
+		api:             &slackAPIMock{},
+		maintenanceFile: "maintenance.flag",
+	}, &config{
+		successTpl:     successTpl,
+		errorTpl:       errorTpl,
+		maintenanceTpl: template.Must(template.New("maintenance").Parse("down")),
+	})
+	handler := auth.handler()
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/auth?code=foo", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, tplSuccess, w.Body.String())
+	<-auth.auths
+
+	assert.Nil(t, ioutil.WriteFile("maintenance.flag", nil, 0777))
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/auth?code=foo", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "down", w.Body.String())
+
+	assert.Nil(t, os.Remove("maintenance.flag"))
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/auth?code=foo", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestInstallNotification(t *testing.T) {
+	messages := make(chan *slack.WebhookMessage, 1)
+	auth := withConfig(&slackAuth{
+		auths:               make(chan AuthEvent, 1),
+		api:                 &slackAPIMock{},
+		notificationWebhook: "https://hooks.slack.com/services/foo",
+		postWebhook: func(url string, msg *slack.WebhookMessage) error {
+			assert.Equal(t, "https://hooks.slack.com/services/foo", url)
+			messages <- msg
+			return nil
+		},
+	}, &config{
+		successTpl: template.Must(template.New("success").Parse(tplSuccess)),
+		errorTpl:   template.Must(template.New("error").Parse(tplError)),
+	})
+
+	w := httptest.NewRecorder()
+	auth.handler().ServeHTTP(w, httptest.NewRequest("GET", "/auth?code=foo", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	select {
+	case msg := <-messages:
+		assert.Contains(t, msg.Text, "New install")
+	case <-time.After(time.Second):
+		t.Fatal("install notification was not posted")
+	}
+}
+
+func TestInstallNotificationError(t *testing.T) {
+	auth := withConfig(&slackAuth{
+		auths:               make(chan AuthEvent, 1),
+		api:                 &slackAPIMock{},
+		notificationWebhook: "https://hooks.slack.com/services/foo",
+		postWebhook: func(url string, msg *slack.WebhookMessage) error {
+			return errors.New("webhook is down")
+		},
+	}, &config{
+		successTpl: template.Must(template.New("success").Parse(tplSuccess)),
+		errorTpl:   template.Must(template.New("error").Parse(tplError)),
+	})
+	handled := make(chan error, 1)
+	auth.OnError(func(err error, r *http.Request) { handled <- err })
+
+	w := httptest.NewRecorder()
+	auth.handler().ServeHTTP(w, httptest.NewRequest("GET", "/auth?code=foo", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	select {
+	case err := <-handled:
+		assert.EqualError(t, err, "webhook is down")
+	case <-time.After(time.Second):
+		t.Fatal("install notification error was not handled")
+	}
+}
+
+func TestHandleCallback(t *testing.T) {
+	auth := &slackAuth{api: &slackAPIMock{}}
+
+	var received *slack.OAuthResponse
+	auth.OnAuth(func(auth *slack.OAuthResponse) {
+		received = auth
+	})
+
+	resp, err := auth.HandleCallback(context.Background(), "foo")
+	assert.Nil(t, err)
+	assert.Equal(t, "foo", resp.AccessToken)
+	assert.Equal(t, resp, received)
+
+	received = nil
+	_, err = auth.HandleCallback(context.Background(), "invalid")
+	assert.NotNil(t, err)
+	assert.Nil(t, received)
+}
+
+func TestSecureHeaders(t *testing.T) {
+	auth := withConfig(&slackAuth{
+		hstsMaxAge: defaultHSTSMaxAge,
+	}, &config{
+		buttonTpl: template.Must(template.New("button").Parse(tplSlackButton)),
+	})
+
+	w := httptest.NewRecorder()
+	auth.handler().ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+	assert.Equal(t, "", w.Header().Get("Strict-Transport-Security"))
+
+	auth.certFile, auth.keyFile = "cert.pem", "key.pem"
+	w = httptest.NewRecorder()
+	auth.handler().ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, "max-age=31536000", w.Header().Get("Strict-Transport-Security"))
+
+	auth.hstsMaxAge = -1
+	w = httptest.NewRecorder()
+	auth.handler().ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, "", w.Header().Get("Strict-Transport-Security"))
+}
+
+type slackAPIStub struct {
+	resp      *slack.OAuthResponse
+	err       error
+	calls     int
+	authTests int
+}
+
+func (f *slackAPIStub) GetOAuthResponse(ctx context.Context, id, secret, code string, debug bool) (*slack.OAuthResponse, error) {
+	f.calls++
+	return f.resp, f.err
+}
+
+func (f *slackAPIStub) GetOAuthV2Response(ctx context.Context, id, secret, code string, debug bool) (*OAuthV2Response, error) {
+	f.calls++
+	return (&slackAPIMock{}).GetOAuthV2Response(ctx, id, secret, code, debug)
+}
+
+func (f *slackAPIStub) AuthTest(ctx context.Context, token string) (*slack.AuthTestResponse, error) {
+	f.authTests++
+	return (&slackAPIMock{}).AuthTest(ctx, token)
+}
+
+func (f *slackAPIStub) GetUserIdentity(ctx context.Context, token string) (*slack.UserIdentityResponse, error) {
+	return (&slackAPIMock{}).GetUserIdentity(ctx, token)
+}
+
+func (f *slackAPIStub) GetTeamInfo(ctx context.Context, token string) (*slack.TeamInfo, error) {
+	return (&slackAPIMock{}).GetTeamInfo(ctx, token)
+}
+
+func TestSlackAPIFallback(t *testing.T) {
+	fallback := &slackAPIMock{}
+	auth := &slackAuth{
+		api:         &slackAPIStub{err: errors.New("connection reset by peer")},
+		fallbackAPI: fallback,
+	}
+
+	event, err := auth.exchange(context.Background(), "foo", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "foo", event.Response.AccessToken)
+
+	primary := &slackAPIStub{err: errors.New("invalid_code")}
+	secondary := &slackAPIStub{err: errors.New("invalid_code")}
+	auth = &slackAuth{api: primary, fallbackAPI: secondary}
+	_, err = auth.exchange(context.Background(), "foo", nil)
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, primary.calls)
+	assert.Equal(t, 0, secondary.calls)
+}
+
+func TestOnAuthEvent(t *testing.T) {
+	auth := withConfig(&slackAuth{
+		auths: make(chan AuthEvent, 1),
+		api:   &slackAPIMock{},
+	}, &config{
+		successTpl: template.Must(template.New("success").Parse(tplSuccess)),
+		errorTpl:   template.Must(template.New("error").Parse(tplError)),
+	})
+
+	var events []AuthEvent
+	auth.OnAuthEvent(func(event AuthEvent) {
+		events = append(events, event)
+	})
+
+	auth.handler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/auth?code=foo", nil))
+	auth.dispatch(<-auth.auths)
+
+	auth.source = "my-app"
+	_, err := auth.HandleCallback(context.Background(), "foo")
+	assert.Nil(t, err)
+
+	assert.Equal(t, 2, len(events))
+	assert.Equal(t, "/auth", events[0].Source)
+	assert.Equal(t, "foo", events[0].Request.FormValue("code"))
+	assert.Equal(t, "foo", events[0].Response.AccessToken)
+	assert.Equal(t, "my-app", events[1].Source)
+	assert.Nil(t, events[1].Request)
+}
+
+func TestSuccessDisplayName(t *testing.T) {
+	auth := withConfig(&slackAuth{
+		auths: make(chan AuthEvent, 2),
+		api:   &slackAPIStub{resp: &slack.OAuthResponse{TeamID: "T123"}},
+	}, &config{
+		successTpl: template.Must(template.New("success").Parse("Welcome {{.DisplayName}}")),
+		errorTpl:   template.Must(template.New("error").Parse(tplError)),
+	})
+
+	w := httptest.NewRecorder()
+	auth.handler().ServeHTTP(w, httptest.NewRequest("GET", "/auth?code=foo", nil))
+	assert.Equal(t, "Welcome T123", w.Body.String())
+
+	auth.api = &slackAPIStub{resp: &slack.OAuthResponse{TeamID: "T123", TeamName: "Foo"}}
+	w = httptest.NewRecorder()
+	auth.handler().ServeHTTP(w, httptest.NewRequest("GET", "/auth?code=foo", nil))
+	assert.Equal(t, "Welcome Foo", w.Body.String())
+}
+
+func TestMissingCode(t *testing.T) {
+	api := &slackAPIStub{}
+	auth := withConfig(&slackAuth{
+		api: api,
+	}, &config{
+		errorTpl: template.Must(template.New("error").Parse("{{.Error}}")),
+	})
+	handler := auth.handler()
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/auth", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "missing authorization code", w.Body.String())
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/auth?error=access_denied", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, "access_denied", w.Body.String())
+
+	assert.Equal(t, 0, api.calls)
+}
+
+func TestCallbackTimeout(t *testing.T) {
+	auth := &slackAuth{api: &slackAPIMock{}, callbackTimeout: 10 * time.Millisecond}
+
+	cancelled := make(chan struct{})
+	auth.OnAuthContext(func(ctx context.Context, event AuthEvent) error {
+		<-ctx.Done()
+		close(cancelled)
+		return ctx.Err()
+	})
+	handled := make(chan error, 2)
+	auth.OnError(func(err error, r *http.Request) { handled <- err })
+
+	_, err := auth.HandleCallback(context.Background(), "foo")
+	assert.Nil(t, err)
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("handler context was not cancelled")
+	}
+	assert.Equal(t, context.DeadlineExceeded, <-handled)
+
+	auth.OnAuthContext(func(ctx context.Context, event AuthEvent) error {
+		return errors.New("db is down")
+	})
+	_, err = auth.HandleCallback(context.Background(), "foo")
+	assert.Nil(t, err)
+	assert.EqualError(t, <-handled, "db is down")
+}
+
+func TestVerifyTokenOnInstall(t *testing.T) {
+	auth := &slackAuth{api: &slackAPIMock{}, verifyToken: true}
+	_, err := auth.HandleCallback(context.Background(), "foo")
+	assert.Nil(t, err)
+
+	auth.api = &slackAPIStub{resp: &slack.OAuthResponse{AccessToken: "revoked"}}
+	_, err = auth.HandleCallback(context.Background(), "foo")
+	assert.NotNil(t, err)
+}
+
+func TestVerifyTokenWithExchangeAPI(t *testing.T) {
+	primary := &slackAPIStub{err: errors.New("connection reset by peer")}
+	fallback := &slackAPIStub{resp: &slack.OAuthResponse{AccessToken: "foo"}}
+	auth := &slackAuth{api: primary, fallbackAPI: fallback, verifyToken: true}
+
+	_, err := auth.exchange(context.Background(), "foo", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, primary.authTests)
+	assert.Equal(t, 1, fallback.authTests)
+}
+
+func TestSuccessRedirect(t *testing.T) {
+	auth := withConfig(&slackAuth{
+		auths:                make(chan AuthEvent, 1),
+		api:                  &slackAPIMock{},
+		successRedirectURL:   "https://app.slack.com",
+		successRedirectDelay: 5 * time.Second,
+	}, &config{
+		successTpl: template.Must(template.New("success").Parse("{{.RedirectDelay}} {{.RedirectURL}}")),
+	})
+
+	w := httptest.NewRecorder()
+	auth.handler().ServeHTTP(w, httptest.NewRequest("GET", "/auth?code=foo", nil))
+	assert.Equal(t, "5; url=https://app.slack.com", w.Header().Get("Refresh"))
+	assert.Equal(t, "5 https://app.slack.com", w.Body.String())
+}
+
+func TestOnAuthWhileDispatching(t *testing.T) {
+	auth := &slackAuth{auths: make(chan AuthEvent, 1)}
+	done := make(chan struct{})
+	go func() {
+		for event := range auth.auths {
+			auth.dispatch(event)
+		}
+		close(done)
+	}()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			auth.auths <- AuthEvent{Response: &slack.OAuthResponse{}}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			auth.OnAuth(func(*slack.OAuthResponse) {})
+			auth.OnAuthEvent(func(AuthEvent) {})
+		}
+	}()
+
+	wg.Wait()
+	close(auth.auths)
+	<-done
+}
+
+func TestButtonHead(t *testing.T) {
+	auth := withConfig(&slackAuth{}, &config{
+		buttonTpl: template.Must(template.New("button").Parse(`{{template "missing"}}`)),
+		errorTpl:  template.Must(template.New("error").Parse(tplError)),
+	})
+
+	w := httptest.NewRecorder()
+	auth.handler().ServeHTTP(w, httptest.NewRequest("HEAD", "/", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, DefaultContentType, w.Header().Get("Content-Type"))
+	assert.Equal(t, "", w.Body.String())
+}
+
+func TestWithoutButton(t *testing.T) {
+	assert.Nil(t, ioutil.WriteFile("valid.txt", []byte("foo"), 0777))
+	defer os.Remove("valid.txt")
+
+	auth, err := New(Options{
+		Addr:         ":8080",
+		ClientID:     "foo",
+		ClientSecret: "bar",
+		SuccessTpl:   "valid.txt",
+		ErrorTpl:     "valid.txt",
+	})
+	assert.Nil(t, err)
+
+	w := httptest.NewRecorder()
+	auth.(*slackAuth).handler().ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestSuccessLogSampling(t *testing.T) {
+	var mut sync.Mutex
+	var msgs []string
+	handler := log15.Root().GetHandler()
+	log15.Root().SetHandler(log15.FuncHandler(func(r *log15.Record) error {
+		mut.Lock()
+		msgs = append(msgs, r.Msg)
+		mut.Unlock()
+		return nil
+	}))
+	defer log15.Root().SetHandler(handler)
+
+	auth := withConfig(&slackAuth{
+		auths:           make(chan AuthEvent, 10),
+		api:             &slackAPIMock{},
+		successSampling: 1e-12,
+	}, &config{
+		successTpl: template.Must(template.New("success").Parse(tplSuccess)),
+		errorTpl:   template.Must(template.New("error").Parse(tplError)),
+	})
+
+	for i := 0; i < 10; i++ {
+		auth.handler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/auth?code=foo", nil))
+		auth.handler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/auth?code=invalid", nil))
+	}
+
+	mut.Lock()
+	defer mut.Unlock()
+	var failures int
+	for _, msg := range msgs {
+		assert.NotEqual(t, "successful authorization", msg)
+		if msg == "error getting oauth response" {
+			failures++
+		}
+	}
+	assert.Equal(t, 10, failures)
+}
+
+func TestRequireAuthHandler(t *testing.T) {
+	auth := &slackAuth{addr: "invalid", requireAuthHandler: true}
+	assert.Equal(t, ErrNoAuthHandler, auth.Run())
+
+	auth.OnAuth(func(*slack.OAuthResponse) {})
+	err := auth.Run()
+	assert.NotNil(t, err)
+	assert.NotEqual(t, ErrNoAuthHandler, err)
+
+	auth = &slackAuth{addr: "invalid", requireAuthHandler: true, installs: make(chan *Install)}
+	assert.NotEqual(t, ErrNoAuthHandler, auth.Run())
+}
+
+func TestNewTrimsOptions(t *testing.T) {
+	assert.Nil(t, ioutil.WriteFile("valid.txt", []byte("foo"), 0777))
+	defer os.Remove("valid.txt")
+
+	s, err := New(Options{
+		Addr:         " :8080\n",
+		ClientID:     "foo ",
+		ClientSecret: " bar",
+		SuccessTpl:   "valid.txt",
+		ErrorTpl:     "valid.txt",
+	})
+	assert.Nil(t, err)
+	auth := s.(*slackAuth)
+	assert.Equal(t, ":8080", auth.addr)
+	assert.Equal(t, "foo", auth.clientID)
+	assert.Equal(t, "bar", auth.clientSecret)
+}
+
+func TestHandler(t *testing.T) {
+	auth := withConfig(&slackAuth{
+		auths: make(chan AuthEvent, 1),
+		api:   &slackAPIMock{},
+	}, &config{
+		successTpl: template.Must(template.New("success").Parse(tplSuccess)),
+		errorTpl:   template.Must(template.New("error").Parse(tplError)),
+	})
+
+	handled := make(chan string, 1)
+	auth.OnAuth(func(resp *slack.OAuthResponse) {
+		handled <- resp.AccessToken
+	})
+
+	mux := http.NewServeMux()
+	mux.Handle("/slack/", http.StripPrefix("/slack", auth.Handler()))
+	// Getting the handler again must not start another consumer.
+	auth.Handler()
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/slack/auth?code=foo", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "foo", <-handled)
+	assert.Nil(t, auth.Stop(context.Background()))
+}
+
+func TestAuthorizationHandlerNilResponse(t *testing.T) {
+	auth := withConfig(&slackAuth{
+		auths: make(chan AuthEvent, 1),
+		api:   &slackAPIStub{},
+	}, &config{
+		successTpl: template.Must(template.New("success").Parse(tplSuccess)),
+		errorTpl:   template.Must(template.New("error").Parse("{{.Error}}")),
+	})
+
+	w := httptest.NewRecorder()
+	auth.handler().ServeHTTP(w, httptest.NewRequest("GET", "/auth?code=foo", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, ErrEmptyOAuthResponse.Error(), w.Body.String())
+	assert.Len(t, auth.auths, 0)
+
+	auth.oauthVersion = OAuthV2
+	_, _, err := auth.oauthResponse(context.Background(), &nilV2API{}, "id", "secret", "foo")
+	assert.Nil(t, err)
+}
+
+// nilV2API returns neither a response nor an error for the v2 exchange.
+type nilV2API struct {
+	slackAPIStub
+}
+
+func (*nilV2API) GetOAuthV2Response(ctx context.Context, id, secret, code string, debug bool) (*OAuthV2Response, error) {
+	return nil, nil
+}
+
+func TestOnError(t *testing.T) {
+	auth := withConfig(&slackAuth{
+		auths: make(chan AuthEvent, 1),
+		api:   &slackAPIMock{},
+	}, &config{
+		successTpl: template.Must(template.New("success").Parse("{{.Missing.Field}}")),
+		errorTpl:   template.Must(template.New("error").Parse(tplError)),
+	})
+
+	// Without a handler errors are only rendered.
+	auth.handler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/auth?code=invalid", nil))
+
+	var errs []string
+	var queries []string
+	auth.OnError(func(err error, r *http.Request) {
+		errs = append(errs, err.Error())
+		queries = append(queries, r.URL.RawQuery)
+	})
+
+	auth.handler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/auth?code=invalid", nil))
+	auth.handler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/auth?error=access_denied", nil))
+	auth.handler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/auth?code=foo", nil))
+	auth.handler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/auth", nil))
+
+	assert.Len(t, errs, 3)
+	assert.Equal(t, "invalid code", errs[0])
+	assert.Equal(t, "access_denied", errs[1])
+	assert.Contains(t, errs[2], "Missing")
+	assert.Equal(t, []string{"code=invalid", "error=access_denied", "code=foo"}, queries)
+}
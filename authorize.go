@@ -0,0 +1,114 @@
+package slackauth
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// DefaultAuthorizeBaseURL is the Slack endpoint users are sent to in order to authorize the app.
+const DefaultAuthorizeBaseURL = "https://slack.com/oauth/authorize"
+
+// DefaultMaxScopeLength is the maximum length of the encoded scope param of the authorize URL
+// accepted by Slack. Longer scope lists make the install fail.
+const DefaultMaxScopeLength = 2000
+
+// DefaultScopeDelimiter is the delimiter used to join the requested scopes.
+const DefaultScopeDelimiter = ","
+
+// joinScopes joins the given scopes with the given delimiter, or the default one if it's empty.
+// All the scopes sent to Slack or rendered in templates must be joined with it.
+func joinScopes(scopes []string, delimiter string) string {
+	if delimiter == "" {
+		delimiter = DefaultScopeDelimiter
+	}
+	return strings.Join(scopes, delimiter)
+}
+
+// joinScopes joins the given scopes with the configured delimiter.
+func (s *slackAuth) joinScopes(scopes []string) string {
+	return joinScopes(scopes, s.scopeDelimiter)
+}
+
+// validateScopeLength checks the encoded scope param for the given scopes is not longer than
+// max characters.
+func validateScopeLength(scopes []string, delimiter string, max int) error {
+	length := len(url.QueryEscape(joinScopes(scopes, delimiter)))
+	if length > max {
+		return fmt.Errorf("slackauth: encoded scopes are %d characters long, the maximum is %d", length, max)
+	}
+	return nil
+}
+
+// authorizeURL returns the Slack authorize URL requesting the given scopes.
+func (s *slackAuth) authorizeURL(scopes []string) string {
+	return s.authorizeURLFor(s.currentClientID(), scopes)
+}
+
+// authorizeURLFor returns the Slack authorize URL for the given client ID requesting the given
+// scopes.
+func (s *slackAuth) authorizeURLFor(clientID string, scopes []string) string {
+	values := url.Values{}
+	values.Set("client_id", clientID)
+	if s.appID != "" {
+		values.Set("app_id", s.appID)
+	}
+	values.Set("scope", s.joinScopes(scopes))
+	if userScope := s.userScopeParam(); userScope != "" {
+		values.Set("user_scope", userScope)
+	}
+	return s.authorizeBaseURL + "?" + values.Encode()
+}
+
+// setConfig builds the authorize URL of the given config and makes it the current one.
+func (s *slackAuth) setConfig(cfg *config) {
+	cfg.authorizeClientID = s.currentClientID()
+	cfg.authorizeURL = s.authorizeURLFor(cfg.authorizeClientID, cfg.configuredScopes())
+	s.cfg.Store(cfg)
+}
+
+// configuredAuthorizeURL returns the authorize URL for the configured scopes. The URL built
+// when the config was loaded is used unless the client ID has been rotated since then.
+func (s *slackAuth) configuredAuthorizeURL(cfg *config) string {
+	clientID := s.currentClientID()
+	if cfg.authorizeURL != "" && cfg.authorizeClientID == clientID {
+		return cfg.authorizeURL
+	}
+	return s.authorizeURLFor(clientID, cfg.configuredScopes())
+}
+
+func (s *slackAuth) AuthorizeURL() string {
+	return s.configuredAuthorizeURL(s.config())
+}
+
+func (s *slackAuth) Scopes() []string {
+	return s.config().configuredScopes()
+}
+
+// configuredScopes returns the scopes the service was configured with.
+func (c *config) configuredScopes() []string {
+	if c.scopes == "" {
+		return nil
+	}
+	return strings.Split(c.scopes, ",")
+}
+
+func (s *slackAuth) UpgradeURL(existingScopes []string) string {
+	granted := make(map[string]bool, len(existingScopes))
+	for _, scope := range existingScopes {
+		granted[scope] = true
+	}
+
+	var missing []string
+	for _, scope := range s.config().configuredScopes() {
+		if !granted[scope] {
+			missing = append(missing, scope)
+		}
+	}
+
+	if len(missing) == 0 {
+		return ""
+	}
+
+	return s.authorizeURL(missing)
+}
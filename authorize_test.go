@@ -0,0 +1,106 @@
+package slackauth
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpgradeURL(t *testing.T) {
+	auth := withConfig(&slackAuth{
+		clientID:         "foo",
+		authorizeBaseURL: DefaultAuthorizeBaseURL,
+	}, &config{
+		scopes: "bot,commands,incoming-webhook",
+	})
+
+	assert.Equal(t,
+		"https://slack.com/oauth/authorize?client_id=foo&scope=commands%2Cincoming-webhook",
+		auth.UpgradeURL([]string{BOT}),
+	)
+	assert.Equal(t, "", auth.UpgradeURL([]string{BOT, COMMANDS, WEBHOOK}))
+}
+
+func TestAuthorizeURL(t *testing.T) {
+	auth := withConfig(&slackAuth{
+		clientID:         "foo",
+		authorizeBaseURL: "http://127.0.0.1:9999/oauth",
+	}, &config{
+		scopes: "bot",
+	})
+
+	assert.Equal(t, "http://127.0.0.1:9999/oauth?client_id=foo&scope=bot", auth.AuthorizeURL())
+}
+
+func TestAuthorizeURLEscaping(t *testing.T) {
+	auth := withConfig(&slackAuth{
+		clientID:         "foo&bar",
+		authorizeBaseURL: DefaultAuthorizeBaseURL,
+	}, &config{
+		scopes: "chat:write,channels:read",
+	})
+
+	u, err := url.Parse(auth.AuthorizeURL())
+	assert.Nil(t, err)
+	assert.Equal(t, "client_id=foo%26bar&scope=chat%3Awrite%2Cchannels%3Aread", u.RawQuery)
+	assert.Equal(t, "chat:write,channels:read", u.Query().Get("scope"))
+	assert.Equal(t, "foo&bar", u.Query().Get("client_id"))
+}
+
+func TestValidateScopeLength(t *testing.T) {
+	assert.Nil(t, validateScopeLength([]string{BOT, COMMANDS}, "", DefaultMaxScopeLength))
+
+	scopes := strings.Split(strings.Repeat("channels:read,", 200), ",")
+	err := validateScopeLength(scopes, "", DefaultMaxScopeLength)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "3600 characters")
+
+	_, err = New(Options{
+		Addr:           ":8080",
+		ClientID:       "foo",
+		ClientSecret:   "bar",
+		MaxScopeLength: 5,
+		ScopeSets:      map[string][]string{"pro": {BOT, COMMANDS}},
+	})
+	assert.NotNil(t, err)
+}
+
+func TestAuthorizeURLCache(t *testing.T) {
+	auth := &slackAuth{
+		clientID:         "foo",
+		authorizeBaseURL: DefaultAuthorizeBaseURL,
+	}
+	auth.setConfig(&config{scopes: "bot,commands"})
+
+	cfg := auth.config()
+	assert.Equal(t, "https://slack.com/oauth/authorize?client_id=foo&scope=bot%2Ccommands", cfg.authorizeURL)
+	assert.Equal(t, cfg.authorizeURL, auth.AuthorizeURL())
+
+	auth.clientID = "bar"
+	assert.Equal(t, "https://slack.com/oauth/authorize?client_id=bar&scope=bot%2Ccommands", auth.AuthorizeURL())
+
+	auth.setConfig(&config{scopes: "bot"})
+	assert.Equal(t, "https://slack.com/oauth/authorize?client_id=bar&scope=bot", auth.config().authorizeURL)
+}
+
+func TestScopes(t *testing.T) {
+	auth := withConfig(&slackAuth{}, &config{scopes: "bot,commands"})
+	assert.Equal(t, []string{BOT, COMMANDS}, auth.Scopes())
+
+	withConfig(auth, &config{})
+	assert.Nil(t, auth.Scopes())
+}
+
+func TestScopeDelimiter(t *testing.T) {
+	assert.Equal(t, "bot,commands", joinScopes([]string{BOT, COMMANDS}, ""))
+	assert.Equal(t, "bot commands", joinScopes([]string{BOT, COMMANDS}, " "))
+
+	auth := withConfig(&slackAuth{
+		clientID:         "foo",
+		authorizeBaseURL: DefaultAuthorizeBaseURL,
+		scopeDelimiter:   " ",
+	}, &config{scopes: "bot,commands"})
+	assert.Equal(t, "https://slack.com/oauth/authorize?client_id=foo&scope=bot+commands", auth.AuthorizeURL())
+}
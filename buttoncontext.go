@@ -0,0 +1,70 @@
+package slackauth
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+// contextCookie is the cookie where the values extracted from the button request are kept
+// until the authorization request.
+const contextCookie = "slackauth_context"
+
+// contextCookieMaxAge is the time users have to complete the authorization after seeing the
+// button for the extracted values to be available.
+const contextCookieMaxAge = time.Hour
+
+// ErrContextWithoutSigner is returned when a ContextExtractor is set without a ParamSigner.
+var ErrContextWithoutSigner = errors.New("slackauth: context extractor requires a param signer")
+
+// setButtonContext stores the values extracted from the button request in a signed cookie.
+func (s *slackAuth) setButtonContext(w http.ResponseWriter, r *http.Request) error {
+	if s.contextExtractor == nil {
+		return nil
+	}
+
+	values := s.contextExtractor(r)
+	if len(values) == 0 {
+		return nil
+	}
+
+	bytes, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+
+	return s.setSignedCookie(w, r, contextCookie, base64.RawURLEncoding.EncodeToString(bytes), contextCookieMaxAge)
+}
+
+// buttonContext returns the values extracted from the button request, if any. Since they are
+// only used for attribution, invalid values are logged and ignored.
+func (s *slackAuth) buttonContext(r *http.Request) map[string]interface{} {
+	if s.contextExtractor == nil {
+		return nil
+	}
+
+	value, ok, err := s.signedCookie(r, contextCookie)
+	if !ok {
+		return nil
+	}
+
+	var bytes []byte
+	if err == nil {
+		bytes, err = base64.RawURLEncoding.DecodeString(value)
+	}
+
+	var values map[string]interface{}
+	if err == nil {
+		err = json.Unmarshal(bytes, &values)
+	}
+
+	if err != nil {
+		log15.Error("error reading button context", "step", "button_context", "err", err.Error())
+		return nil
+	}
+	return values
+}
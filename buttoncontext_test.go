@@ -0,0 +1,56 @@
+package slackauth
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContextExtractor(t *testing.T) {
+	auth := withConfig(&slackAuth{
+		auths:  make(chan AuthEvent, 2),
+		api:    &slackAPIMock{},
+		signer: NewHMACSigner([]byte("secret")),
+		contextExtractor: func(r *http.Request) map[string]interface{} {
+			c, err := r.Cookie("campaign")
+			if err != nil {
+				return nil
+			}
+			return map[string]interface{}{"campaign": c.Value}
+		},
+	}, &config{
+		successTpl: template.Must(template.New("success").Parse(tplSuccess)),
+		errorTpl:   template.Must(template.New("error").Parse(tplError)),
+		buttonTpl:  template.Must(template.New("button").Parse("button")),
+	})
+	handler := auth.handler()
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(&http.Cookie{Name: "campaign", Value: "spring"})
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	var cookie *http.Cookie
+	for _, c := range w.Result().Cookies() {
+		if c.Name == contextCookie {
+			cookie = c
+		}
+	}
+	assert.NotNil(t, cookie)
+
+	r = httptest.NewRequest("GET", "/auth?code=foo", nil)
+	r.AddCookie(cookie)
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	assert.Equal(t, map[string]interface{}{"campaign": "spring"}, (<-auth.auths).Context)
+
+	cookie.Value = "value=e30&sig=foo"
+	r = httptest.NewRequest("GET", "/auth?code=foo", nil)
+	r.AddCookie(cookie)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Nil(t, (<-auth.auths).Context)
+}
@@ -0,0 +1,64 @@
+package slackauth
+
+import (
+	"errors"
+	"html/template"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// defaultButtonIndex is the file of the ButtonFS used as the button template by default.
+const defaultButtonIndex = "index.html"
+
+// configureButtonFS configures the button to be read from the given filesystem, using the
+// index file as the button template.
+func (c *config) configureButtonFS(fs http.FileSystem, index string, scopes []string) error {
+	if len(scopes) == 0 {
+		return errors.New("At least one scope needed")
+	}
+
+	tpl, err := readFSTemplate(fs, buttonIndexPath(index))
+	if err != nil {
+		return err
+	}
+
+	c.scopes = strings.Join(scopes, ",")
+	c.buttonTpl = tpl
+	return nil
+}
+
+// buttonIndexPath returns the path of the button template in the ButtonFS.
+func buttonIndexPath(index string) string {
+	if index == "" {
+		index = defaultButtonIndex
+	}
+	return path.Clean("/" + index)
+}
+
+// serveButtonAsset serves the request from the ButtonFS if it's not a request for the button
+// itself, and reports whether it did so.
+func (s *slackAuth) serveButtonAsset(w http.ResponseWriter, r *http.Request) bool {
+	if s.buttonFS == nil || r.URL.Path == "/" || path.Clean(r.URL.Path) == s.buttonIndex {
+		return false
+	}
+
+	http.FileServer(s.buttonFS).ServeHTTP(w, r)
+	return true
+}
+
+func readFSTemplate(fs http.FileSystem, name string) (*template.Template, error) {
+	f, err := fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	bytes, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+
+	return template.New("").Parse(string(bytes))
+}
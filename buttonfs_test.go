@@ -0,0 +1,41 @@
+package slackauth
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestButtonFS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "slackauth")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "index.html"), []byte("scopes: {{.Scopes}}"), 0777))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "style.css"), []byte("body {}"), 0777))
+
+	cfg := &config{}
+	assert.NotNil(t, cfg.configureButtonFS(http.Dir(dir), "", nil))
+	assert.NotNil(t, cfg.configureButtonFS(http.Dir(dir), "missing.html", []string{BOT}))
+	assert.Nil(t, cfg.configureButtonFS(http.Dir(dir), "", []string{BOT}))
+
+	auth := withConfig(&slackAuth{buttonFS: http.Dir(dir), buttonIndex: buttonIndexPath("")}, cfg)
+	handler := auth.handler()
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, "scopes: bot", w.Body.String())
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/index.html", nil))
+	assert.Equal(t, "scopes: bot", w.Body.String())
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/style.css", nil))
+	assert.Equal(t, "body {}", w.Body.String())
+}
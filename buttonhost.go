@@ -0,0 +1,47 @@
+package slackauth
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"html/template"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var hrefMatcher = regexp.MustCompile(`(?i)href\s*=\s*["']([^"']*)["']`)
+
+// validateButtonHost renders the button template with sample data and checks all absolute
+// links point to slack.com or the host of the authorize base URL.
+func validateButtonHost(tpl *template.Template, scopes, authorizeBaseURL string) error {
+	base, err := url.Parse(authorizeBaseURL)
+	if err != nil {
+		return err
+	}
+
+	allowed := map[string]bool{"slack.com": true, base.Hostname(): true}
+	sample := map[string]string{
+		"Scopes":       scopes,
+		"ClientId":     "client-id",
+		"AuthorizeURL": authorizeBaseURL + "?" + url.Values{"scope": {scopes}}.Encode(),
+	}
+
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, sample); err != nil {
+		return err
+	}
+
+	for _, m := range hrefMatcher.FindAllStringSubmatch(buf.String(), -1) {
+		link, err := url.Parse(strings.TrimSpace(html.UnescapeString(m[1])))
+		if err != nil {
+			return fmt.Errorf("slackauth: invalid link in button template: %s", err)
+		}
+
+		if link.Host != "" && !allowed[link.Hostname()] {
+			return fmt.Errorf("slackauth: button template links to a host that is not allowed: %s", link.Hostname())
+		}
+	}
+
+	return nil
+}
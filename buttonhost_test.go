@@ -0,0 +1,32 @@
+package slackauth
+
+import (
+	"html/template"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateButtonHost(t *testing.T) {
+	cases := []struct {
+		tpl  string
+		base string
+		err  bool
+	}{
+		{tplSlackButton, DefaultAuthorizeBaseURL, false},
+		{`<a href="{{.AuthorizeURL}}">Add</a><link href="/style.css">`, DefaultAuthorizeBaseURL, false},
+		{`<a href="{{.AuthorizeURL}}">Add</a>`, "http://127.0.0.1:9999/oauth", false},
+		{`<a href="https://slack.com.evil.com/oauth/authorize">Add</a>`, DefaultAuthorizeBaseURL, true},
+		{`<a HREF='https://evil.com/?scope={{.Scopes}}'>Add</a>`, DefaultAuthorizeBaseURL, true},
+	}
+
+	for _, c := range cases {
+		tpl := template.Must(template.New("button").Parse(c.tpl))
+		err := validateButtonHost(tpl, "bot", c.base)
+		if c.err {
+			assert.NotNil(t, err, c.tpl)
+		} else {
+			assert.Nil(t, err, c.tpl)
+		}
+	}
+}
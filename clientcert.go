@@ -0,0 +1,39 @@
+package slackauth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"os"
+)
+
+// loadClientCAs reads the PEM encoded certificates of the CAs that sign the client
+// certificates.
+func loadClientCAs(file string) (*x509.CertPool, error) {
+	if file == "" {
+		return nil, errors.New("slackauth: client CA file is required to verify client certificates")
+	}
+
+	bytes, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(bytes) {
+		return nil, errors.New("slackauth: no valid certificates found in client CA file")
+	}
+	return pool, nil
+}
+
+// tlsConfig returns the TLS configuration of the server, or nil to use the default one.
+func (s *slackAuth) tlsConfig() *tls.Config {
+	if s.clientCAs == nil {
+		return nil
+	}
+
+	return &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  s.clientCAs,
+	}
+}
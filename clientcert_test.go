@@ -0,0 +1,82 @@
+package slackauth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"html/template"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestCert returns a certificate signed by the given parent, or self-signed if it's nil.
+func newTestCert(t *testing.T, parent *tls.Certificate, ca bool) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+
+	tpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: "slackauth test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  ca,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	signerCert, signerKey := tpl, interface{}(key)
+	if parent != nil {
+		signerCert, signerKey = parent.Leaf, parent.PrivateKey
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tpl, signerCert, &key.PublicKey, signerKey)
+	assert.Nil(t, err)
+	leaf, err := x509.ParseCertificate(der)
+	assert.Nil(t, err)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+func TestRequireClientCert(t *testing.T) {
+	ca := newTestCert(t, nil, true)
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	assert.Nil(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Certificate[0]}), 0600))
+
+	pool, err := loadClientCAs(caFile)
+	assert.Nil(t, err)
+
+	auth := withConfig(&slackAuth{clientCAs: pool}, &config{
+		buttonTpl: template.Must(template.New("button").Parse("button")),
+		errorTpl:  template.Must(template.New("error").Parse(tplError)),
+	})
+	srv := httptest.NewUnstartedServer(auth.handler())
+	srv.TLS = auth.tlsConfig()
+	srv.StartTLS()
+	defer srv.Close()
+
+	_, err = srv.Client().Get(srv.URL)
+	assert.NotNil(t, err)
+
+	client := srv.Client()
+	client.Transport.(*http.Transport).TLSClientConfig.Certificates = []tls.Certificate{newTestCert(t, &ca, false)}
+	resp, err := client.Get(srv.URL)
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	_, err = loadClientCAs("")
+	assert.NotNil(t, err)
+	_, err = loadClientCAs(filepath.Join(t.TempDir(), "missing.pem"))
+	assert.NotNil(t, err)
+}
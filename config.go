@@ -0,0 +1,257 @@
+package slackauth
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"os"
+	"strings"
+
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+// config is the part of the service configuration that can be changed with Reload. Once
+// published, a config is never modified, so handlers can keep using the one they loaded
+// at the start of the request while a new one is published.
+type config struct {
+	successTpl     *template.Template
+	errorTpl       *template.Template
+	buttonTpl      *template.Template
+	maintenanceTpl *template.Template
+	gateTpl        *template.Template
+	consentTpl     *template.Template
+	reinstallTpl   *template.Template
+	rateLimitTpl   *template.Template
+	cooldownTpl    *template.Template
+	buttonVariants map[string]*template.Template
+	scopes         string
+
+	successContentType string
+	errorContentType   string
+	buttonContentType  string
+
+	maxResponseBytes int
+
+	// authorizeURL is the authorize URL for the configured scopes, built with the
+	// authorizeClientID client ID.
+	authorizeURL      string
+	authorizeClientID string
+
+	// successSrc, errorSrc and buttonSrc are copies of the templates made before any of them
+	// is executed, since executed templates can't be cloned anymore.
+	successSrc *template.Template
+	errorSrc   *template.Template
+	buttonSrc  *template.Template
+}
+
+// ErrTemplateSourceConflict is returned when a template is given both as a path and as a
+// string.
+var ErrTemplateSourceConflict = errors.New("slackauth: template path and string can not be used together")
+
+// templateSource parses the template given as a string, if any, or reads it from the given
+// path otherwise. The name is only used in the error returned if both are set.
+func templateSource(name, file, text string) (*template.Template, error) {
+	if file != "" && text != "" {
+		return nil, fmt.Errorf("%w: %s", ErrTemplateSourceConflict, name)
+	}
+
+	if text != "" {
+		return template.New("").Parse(text)
+	}
+	return readTemplate(file)
+}
+
+// loadConfig reads all the templates referenced in the given options.
+func loadConfig(opts Options) (*config, error) {
+	successTpl, err := templateSource("success", opts.SuccessTpl, opts.SuccessTplString)
+	if err != nil {
+		return nil, err
+	}
+
+	errorTpl, err := templateSource("error", opts.ErrorTpl, opts.ErrorTplString)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &config{
+		successTpl:         successTpl,
+		errorTpl:           errorTpl,
+		successContentType: opts.SuccessContentType,
+		errorContentType:   opts.ErrorContentType,
+		buttonContentType:  opts.ButtonContentType,
+		maxResponseBytes:   opts.MaxResponseBytes,
+	}
+	if opts.MaintenanceTpl != "" {
+		cfg.maintenanceTpl, err = readTemplate(opts.MaintenanceTpl)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.GateTpl != "" {
+		cfg.gateTpl, err = readTemplate(opts.GateTpl)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	cfg.buttonVariants, err = readButtonVariants(opts.ButtonVariants)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.ReinstallTpl != "" {
+		cfg.reinstallTpl, err = readTemplate(opts.ReinstallTpl)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.ConsentTpl != "" {
+		cfg.consentTpl, err = readTemplate(opts.ConsentTpl)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.RateLimitTpl != "" {
+		cfg.rateLimitTpl, err = readTemplate(opts.RateLimitTpl)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.CooldownTpl != "" {
+		cfg.cooldownTpl, err = readTemplate(opts.CooldownTpl)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.ButtonTplString != "" && (opts.ButtonTpl != "" || opts.ButtonFS != nil) {
+		return nil, fmt.Errorf("%w: button", ErrTemplateSourceConflict)
+	}
+
+	if opts.ButtonFS != nil {
+		err = cfg.configureButtonFS(opts.ButtonFS, opts.ButtonIndex, opts.Scopes)
+	} else {
+		err = cfg.configureButton(opts.ButtonTpl, opts.ButtonTplString, opts.Scopes)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// The copies must be taken before validating the button host, which executes the
+	// button template.
+	if cfg.successSrc, err = cloneTemplate(cfg.successTpl); err != nil {
+		return nil, err
+	}
+	if cfg.errorSrc, err = cloneTemplate(cfg.errorTpl); err != nil {
+		return nil, err
+	}
+	if cfg.buttonSrc, err = cloneTemplate(cfg.buttonTpl); err != nil {
+		return nil, err
+	}
+
+	if opts.ValidateButtonHost && cfg.buttonTpl != nil {
+		authorizeBaseURL := opts.AuthorizeBaseURL
+		if authorizeBaseURL == "" {
+			authorizeBaseURL = defaultAuthorizeBaseURL(opts.OAuthVersion)
+		}
+
+		scopes := joinScopes(cfg.configuredScopes(), opts.ScopeDelimiter)
+		if err := validateButtonHost(cfg.buttonTpl, scopes, authorizeBaseURL); err != nil {
+			return nil, err
+		}
+	}
+
+	return cfg, nil
+}
+
+// cloneTemplate returns a clone of the given template, or nil if there is no template.
+func cloneTemplate(tpl *template.Template) (*template.Template, error) {
+	if tpl == nil {
+		return nil, nil
+	}
+	return tpl.Clone()
+}
+
+// templateCopy returns a copy of the given unexecuted template, which can be freely modified.
+func templateCopy(tpl *template.Template) *template.Template {
+	clone, err := cloneTemplate(tpl)
+	if err != nil {
+		log15.Error("error copying template", "step", "copy_template", "err", err.Error())
+		return nil
+	}
+	return clone
+}
+
+func (s *slackAuth) SuccessTemplate() *template.Template {
+	return templateCopy(s.config().successSrc)
+}
+
+func (s *slackAuth) ErrorTemplate() *template.Template {
+	return templateCopy(s.config().errorSrc)
+}
+
+func (s *slackAuth) ButtonTemplate() *template.Template {
+	return templateCopy(s.config().buttonSrc)
+}
+
+func (c *config) configureButton(file, text string, scopes []string) error {
+	if len(file) > 0 || len(text) > 0 {
+		buttonTpl, err := templateSource("button", file, text)
+		if err != nil {
+			return err
+		}
+
+		if len(scopes) == 0 {
+			return errors.New("At least one scope needed")
+		}
+
+		c.scopes = strings.Join(scopes, ",")
+		c.buttonTpl = buttonTpl
+	}
+
+	return nil
+}
+
+// DefaultContentType is the content type of the pages rendered from templates, unless another
+// one is configured.
+const DefaultContentType = "text/html; charset=utf-8"
+
+// setContentType sets the given content type in the response, or the default one if it's
+// empty.
+func setContentType(w http.ResponseWriter, contentType string) {
+	if contentType == "" {
+		contentType = DefaultContentType
+	}
+	w.Header().Set("Content-Type", contentType)
+}
+
+// config returns the current configuration of the service.
+func (s *slackAuth) config() *config {
+	return s.cfg.Load()
+}
+
+// reloadOnSignal reloads the configuration every time a signal is received on the given
+// channel, until it's closed.
+func (s *slackAuth) reloadOnSignal(signals <-chan os.Signal) {
+	for sig := range signals {
+		log15.Info("reloading configuration", "signal", sig.String())
+		s.Reload()
+	}
+}
+
+func (s *slackAuth) Reload() error {
+	cfg, err := loadConfig(s.opts)
+	if err != nil {
+		log15.Error("error reloading configuration", "err", err.Error())
+		return err
+	}
+
+	s.setConfig(cfg)
+	log15.Info("configuration reloaded")
+	return nil
+}
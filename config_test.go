@@ -0,0 +1,224 @@
+package slackauth
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// withConfig publishes the given config in the service and returns the service.
+func withConfig(s *slackAuth, cfg *config) *slackAuth {
+	s.cfg.Store(cfg)
+	return s
+}
+
+func TestReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "slackauth")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	tplPath := func(name string) string {
+		return filepath.Join(dir, name)
+	}
+
+	assert.Nil(t, ioutil.WriteFile(tplPath("success.html"), []byte(tplSuccess), 0777))
+	assert.Nil(t, ioutil.WriteFile(tplPath("error.html"), []byte(tplError), 0777))
+	assert.Nil(t, ioutil.WriteFile(tplPath("button.html"), []byte("v1"), 0777))
+
+	svc, err := New(Options{
+		Addr:         ":8080",
+		ClientID:     "foo",
+		ClientSecret: "bar",
+		SuccessTpl:   tplPath("success.html"),
+		ErrorTpl:     tplPath("error.html"),
+		ButtonTpl:    tplPath("button.html"),
+		Scopes:       []string{BOT},
+	})
+	assert.Nil(t, err)
+	auth := svc.(*slackAuth)
+	auth.api = &slackAPIMock{}
+	handler := auth.handler()
+
+	go func() {
+		for range auth.auths {
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				for _, url := range []string{"/", "/auth?code=foo", "/auth?code=invalid"} {
+					w := httptest.NewRecorder()
+					handler.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < 20; i++ {
+		assert.Nil(t, auth.Reload())
+	}
+	wg.Wait()
+
+	assert.Nil(t, ioutil.WriteFile(tplPath("button.html"), []byte("v2"), 0777))
+	assert.Nil(t, auth.Reload())
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, "v2", w.Body.String())
+
+	assert.Nil(t, os.Remove(tplPath("button.html")))
+	assert.NotNil(t, auth.Reload())
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, "v2", w.Body.String())
+}
+
+func TestReloadOnSignal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "slackauth")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	button := filepath.Join(dir, "button.html")
+	assert.Nil(t, ioutil.WriteFile(button, []byte("v1"), 0777))
+
+	opts := Options{SuccessTpl: button, ErrorTpl: button, ButtonTpl: button, Scopes: []string{BOT}}
+	cfg, err := loadConfig(opts)
+	assert.Nil(t, err)
+	auth := withConfig(&slackAuth{opts: opts}, cfg)
+
+	assert.Nil(t, ioutil.WriteFile(button, []byte("v2"), 0777))
+	signals := make(chan os.Signal)
+	done := make(chan struct{})
+	go func() {
+		auth.reloadOnSignal(signals)
+		close(done)
+	}()
+	signals <- syscall.SIGHUP
+	close(signals)
+	<-done
+
+	w := httptest.NewRecorder()
+	auth.handler().ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, "v2", w.Body.String())
+}
+
+func TestContentType(t *testing.T) {
+	auth := withConfig(&slackAuth{
+		auths: make(chan AuthEvent, 2),
+		api:   &slackAPIMock{},
+	}, &config{
+		successTpl:         template.Must(template.New("success").Parse("success")),
+		errorTpl:           template.Must(template.New("error").Parse("error")),
+		buttonTpl:          template.Must(template.New("button").Parse("button")),
+		successContentType: "text/plain; charset=utf-8",
+		buttonContentType:  "application/xml",
+	})
+	handler := auth.handler()
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/auth?code=foo", nil))
+	assert.Equal(t, "text/plain; charset=utf-8", w.Header().Get("Content-Type"))
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, "application/xml", w.Header().Get("Content-Type"))
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/auth", nil))
+	assert.Equal(t, DefaultContentType, w.Header().Get("Content-Type"))
+}
+
+func TestTemplateAccessors(t *testing.T) {
+	dir := t.TempDir()
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "success.html"), []byte("hello {{.DisplayName}}"), 0600))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "error.html"), []byte(tplError), 0600))
+
+	svc, err := New(Options{
+		Addr:         ":8080",
+		ClientID:     "foo",
+		ClientSecret: "bar",
+		SuccessTpl:   filepath.Join(dir, "success.html"),
+		ErrorTpl:     filepath.Join(dir, "error.html"),
+	})
+	assert.Nil(t, err)
+	auth := svc.(*slackAuth)
+	auth.api = &slackAPIMock{}
+	assert.Nil(t, svc.ButtonTemplate())
+
+	w := httptest.NewRecorder()
+	auth.handler().ServeHTTP(w, httptest.NewRequest("GET", "/auth?code=invalid", nil))
+	assert.Equal(t, tplError, w.Body.String())
+
+	var buf bytes.Buffer
+	assert.Nil(t, svc.ErrorTemplate().Execute(&buf, nil))
+	assert.Equal(t, tplError, buf.String())
+
+	tpl := svc.SuccessTemplate()
+	template.Must(tpl.Parse("changed"))
+	buf.Reset()
+	assert.Nil(t, svc.SuccessTemplate().Execute(&buf, successData{DisplayName: "Acme"}))
+	assert.Equal(t, "hello Acme", buf.String())
+}
+
+func TestTemplateStrings(t *testing.T) {
+	cfg, err := loadConfig(Options{
+		SuccessTplString: "success {{.DisplayName}}",
+		ErrorTplString:   "error {{.Error}}",
+		ButtonTplString:  "button {{.Scopes}}",
+		Scopes:           []string{BOT},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "bot", cfg.scopes)
+
+	var buf bytes.Buffer
+	assert.Nil(t, cfg.buttonTpl.Execute(&buf, map[string]string{"Scopes": "bot"}))
+	assert.Equal(t, "button bot", buf.String())
+
+	assert.Nil(t, ioutil.WriteFile("valid.txt", []byte("foo"), 0777))
+	defer os.Remove("valid.txt")
+
+	_, err = loadConfig(Options{SuccessTpl: "valid.txt", SuccessTplString: "foo", ErrorTpl: "valid.txt"})
+	assert.True(t, errors.Is(err, ErrTemplateSourceConflict))
+	assert.EqualError(t, err, "slackauth: template path and string can not be used together: success")
+
+	_, err = loadConfig(Options{SuccessTpl: "valid.txt", ErrorTpl: "valid.txt", ErrorTplString: "foo"})
+	assert.True(t, errors.Is(err, ErrTemplateSourceConflict))
+
+	_, err = loadConfig(Options{
+		SuccessTpl:      "valid.txt",
+		ErrorTpl:        "valid.txt",
+		ButtonTpl:       "valid.txt",
+		ButtonTplString: "foo",
+		Scopes:          []string{BOT},
+	})
+	assert.True(t, errors.Is(err, ErrTemplateSourceConflict))
+}
+
+func TestLoadConfigValidateButtonHost(t *testing.T) {
+	cfg, err := loadConfig(Options{
+		SuccessTplString:   "success",
+		ErrorTplString:     "error",
+		ButtonTplString:    `<a href="{{.AuthorizeURL}}">Add</a>`,
+		Scopes:             []string{BOT},
+		ValidateButtonHost: true,
+	})
+	assert.Nil(t, err)
+	assert.NotNil(t, cfg.buttonSrc)
+
+	auth := withConfig(&slackAuth{}, cfg)
+	var buf bytes.Buffer
+	assert.Nil(t, auth.ButtonTemplate().Execute(&buf, map[string]string{"AuthorizeURL": DefaultAuthorizeBaseURL}))
+	assert.Equal(t, `<a href="https://slack.com/oauth/authorize">Add</a>`, buf.String())
+}
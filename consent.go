@@ -0,0 +1,61 @@
+package slackauth
+
+import (
+	"net/http"
+
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+// consentPath is the route the consent page form is posted to once the user accepts.
+const consentPath = "/consent"
+
+// consentHandler redirects users who accepted the consent page to the authorize URL.
+func (s *slackAuth) consentHandler(w http.ResponseWriter, r *http.Request) {
+	cfg := s.config()
+	if cfg.consentTpl == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		cfg.renderError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	if s.gateButton(w, r) {
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		log15.Error("error parsing form", "step", "parse_form", "err", err.Error())
+		cfg.renderError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+
+	scopes, _, err := s.requestedScopes(cfg, r)
+	if err == nil && s.optionalBot {
+		scopes, err = installScopes(scopes, r.FormValue(includeBotParam) == "true")
+	}
+	if err != nil {
+		log15.Error("error selecting scopes", "step", "select_scopes", "err", err.Error())
+		cfg.renderError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := s.setScopesCookie(w, r, scopes); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		log15.Error("error signing scopes", "step", "sign_scopes", "err", err.Error())
+		return
+	}
+
+	state, err := s.setState(w, r)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		log15.Error("error generating state", "step", "generate_state", "err", err.Error())
+		return
+	}
+
+	log15.Debug("consent accepted", "step", "consent", "user agent", r.UserAgent())
+	http.Redirect(w, r, withState(s.authorizeURL(scopes), state), http.StatusFound)
+}
@@ -0,0 +1,42 @@
+package slackauth
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConsent(t *testing.T) {
+	auth := withConfig(&slackAuth{
+		clientID:         "foo",
+		authorizeBaseURL: DefaultAuthorizeBaseURL,
+		scopeSetParam:    defaultScopeSetParam,
+		scopeSets:        map[string][]string{"pro": {BOT, COMMANDS}},
+		consent:          true,
+	}, &config{
+		buttonTpl:  template.Must(template.New("button").Parse("button")),
+		consentTpl: template.Must(template.New("consent").Parse("terms {{.ConsentURL}}")),
+		errorTpl:   template.Must(template.New("error").Parse(tplError)),
+		scopes:     BOT,
+	})
+	handler := auth.handler()
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, "terms /consent", w.Body.String())
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/consent", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+
+	r := httptest.NewRequest("POST", "/consent", strings.NewReader("plan=pro"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "https://slack.com/oauth/authorize?client_id=foo&scope=bot%2Ccommands", w.Header().Get("Location"))
+}
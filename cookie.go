@@ -0,0 +1,71 @@
+package slackauth
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// setSignedCookie sets a cookie with the given value signed with the ParamSigner, so it can
+// be read back in the authorization request. The name of the cookie and its expiry are signed
+// along with the value, so it can't be replayed as another cookie or after it expires. It must
+// only be used when there's a signer.
+func (s *slackAuth) setSignedCookie(w http.ResponseWriter, r *http.Request, name, value string, maxAge time.Duration) error {
+	values := url.Values{
+		"name":    {name},
+		"value":   {value},
+		"expires": {strconv.FormatInt(time.Now().Add(maxAge).Unix(), 10)},
+	}
+	sig, err := s.signer.Sign(values)
+	if err != nil {
+		return err
+	}
+	values.Set(signatureParam, sig)
+	values.Del("name")
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    values.Encode(),
+		Path:     "/",
+		MaxAge:   int(maxAge / time.Second),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
+
+// signedCookie returns the value of the given signed cookie and whether it was sent. An error
+// is returned if the signature does not match the value, the name or the expiry of the cookie,
+// or if it expired.
+func (s *slackAuth) signedCookie(r *http.Request, name string) (string, bool, error) {
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return "", false, nil
+	}
+
+	values, err := url.ParseQuery(cookie.Value)
+	if err != nil {
+		return "", true, ErrInvalidSignature
+	}
+
+	sig := values.Get(signatureParam)
+	values.Del(signatureParam)
+	values.Set("name", name)
+	if err := s.signer.Verify(values, sig); err != nil {
+		return "", true, err
+	}
+
+	expires, err := strconv.ParseInt(values.Get("expires"), 10, 64)
+	if err != nil || time.Now().Unix() >= expires {
+		return "", true, fmt.Errorf("%w: cookie %q expired", ErrInvalidSignature, name)
+	}
+	return values.Get("value"), true, nil
+}
+
+// clearCookie removes the given cookie.
+func clearCookie(w http.ResponseWriter, name string) {
+	http.SetCookie(w, &http.Cookie{Name: name, Path: "/", MaxAge: -1})
+}
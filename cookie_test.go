@@ -0,0 +1,56 @@
+package slackauth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignedCookie(t *testing.T) {
+	auth := &slackAuth{signer: NewHMACSigner([]byte("secret"))}
+
+	w := httptest.NewRecorder()
+	assert.Nil(t, auth.setSignedCookie(w, httptest.NewRequest("GET", "/", nil), "foo", "bar", time.Minute))
+	cookie := w.Result().Cookies()[0]
+
+	read := func(c *http.Cookie) (string, bool, error) {
+		r := httptest.NewRequest("GET", "/auth", nil)
+		r.AddCookie(c)
+		return auth.signedCookie(r, "foo")
+	}
+
+	value, ok, err := read(cookie)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "bar", value)
+
+	_, ok, err = auth.signedCookie(httptest.NewRequest("GET", "/auth", nil), "foo")
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	// The value of another cookie is not accepted.
+	r := httptest.NewRequest("GET", "/auth", nil)
+	r.AddCookie(&http.Cookie{Name: "baz", Value: cookie.Value})
+	_, ok, err = auth.signedCookie(r, "baz")
+	assert.True(t, ok)
+	assert.True(t, errors.Is(err, ErrInvalidSignature))
+
+	values := url.Values{
+		"name":    {"foo"},
+		"value":   {"bar"},
+		"expires": {strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10)},
+	}
+	sig, err := auth.signer.Sign(values)
+	assert.Nil(t, err)
+	values.Set(signatureParam, sig)
+	values.Del("name")
+	_, ok, err = read(&http.Cookie{Name: "foo", Value: values.Encode()})
+	assert.True(t, ok)
+	assert.True(t, errors.Is(err, ErrInvalidSignature))
+}
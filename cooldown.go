@@ -0,0 +1,109 @@
+package slackauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/nlopes/slack"
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+// ErrReinstallCooldown is returned when a team installs the app again before the
+// ReinstallCooldown has passed since its last install.
+var ErrReinstallCooldown = errors.New("slackauth: the app was installed recently, try again later")
+
+// CooldownStore keeps track of the last install of every team for the ReinstallCooldown.
+type CooldownStore interface {
+	// Allowed reports whether the team with the given ID did not install the app within the
+	// given cooldown.
+	Allowed(ctx context.Context, teamID string, cooldown time.Duration) (bool, error)
+	// Record records an install of the team with the given ID. It's only called once the
+	// install has been saved.
+	Record(ctx context.Context, teamID string) error
+}
+
+// memoryCooldownStore is the CooldownStore used by default, which only works for a single
+// instance.
+type memoryCooldownStore struct {
+	now      func() time.Time
+	mut      sync.Mutex
+	installs map[string]time.Time
+}
+
+func newMemoryCooldownStore() *memoryCooldownStore {
+	return &memoryCooldownStore{now: time.Now, installs: make(map[string]time.Time)}
+}
+
+func (m *memoryCooldownStore) Allowed(ctx context.Context, teamID string, cooldown time.Duration) (bool, error) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	now := m.now()
+	for team, at := range m.installs {
+		if now.Sub(at) >= cooldown {
+			delete(m.installs, team)
+		}
+	}
+
+	_, ok := m.installs[teamID]
+	return !ok, nil
+}
+
+func (m *memoryCooldownStore) Record(ctx context.Context, teamID string) error {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	m.installs[teamID] = m.now()
+	return nil
+}
+
+// checkCooldown returns an ErrReinstallCooldown error with the team ID if the team of the
+// given response installed the app within the ReinstallCooldown. The tokens are not revoked,
+// since Slack may return the ones the team is already using. Store errors are logged and the
+// install is allowed.
+func (s *slackAuth) checkCooldown(ctx context.Context, resp *slack.OAuthResponse) error {
+	if s.reinstallCooldown <= 0 || resp.TeamID == "" {
+		return nil
+	}
+
+	ok, err := s.cooldowns.Allowed(ctx, resp.TeamID, s.reinstallCooldown)
+	if err != nil {
+		log15.Error("error checking reinstall cooldown", "step", "reinstall_cooldown", "team id", resp.TeamID, "err", err.Error())
+		return nil
+	}
+	if ok {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %q", ErrReinstallCooldown, resp.TeamID)
+}
+
+// recordCooldown records the install of the team of the given response for the
+// ReinstallCooldown. Store errors are only logged.
+func (s *slackAuth) recordCooldown(ctx context.Context, resp *slack.OAuthResponse) {
+	if s.reinstallCooldown <= 0 || resp.TeamID == "" {
+		return
+	}
+
+	if err := s.cooldowns.Record(ctx, resp.TeamID); err != nil {
+		log15.Error("error recording install for the reinstall cooldown", "step", "reinstall_cooldown", "team id", resp.TeamID, "err", err.Error())
+	}
+}
+
+// renderCooldown renders the cooldown template, or the error template if there is none or it
+// fails.
+func (c *config) renderCooldown(w http.ResponseWriter) {
+	msg := ErrReinstallCooldown.Error()
+	if c.cooldownTpl == nil {
+		c.renderError(w, http.StatusTooManyRequests, msg)
+		return
+	}
+
+	if err := c.render(w, http.StatusTooManyRequests, c.errorContentType, c.cooldownTpl, errorData{Error: msg}); err != nil {
+		log15.Error("error displaying cooldown tpl", "step", "render_cooldown", "err", err.Error())
+		c.renderError(w, http.StatusTooManyRequests, msg)
+	}
+}
@@ -0,0 +1,134 @@
+package slackauth
+
+import (
+	"context"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nlopes/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryCooldownStore(t *testing.T) {
+	now := time.Now()
+	store := newMemoryCooldownStore()
+	store.now = func() time.Time { return now }
+
+	ok, err := store.Allowed(context.Background(), "T1", time.Minute)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Nil(t, store.Record(context.Background(), "T1"))
+
+	ok, _ = store.Allowed(context.Background(), "T1", time.Minute)
+	assert.False(t, ok)
+	ok, _ = store.Allowed(context.Background(), "T2", time.Minute)
+	assert.True(t, ok)
+	assert.Nil(t, store.Record(context.Background(), "T2"))
+
+	now = now.Add(time.Minute)
+	ok, _ = store.Allowed(context.Background(), "T1", time.Minute)
+	assert.True(t, ok)
+	assert.Len(t, store.installs, 0)
+}
+
+type cooldownStoreStub struct {
+	ok  bool
+	err error
+}
+
+func (c cooldownStoreStub) Allowed(ctx context.Context, teamID string, cooldown time.Duration) (bool, error) {
+	return c.ok, c.err
+}
+
+func (c cooldownStoreStub) Record(ctx context.Context, teamID string) error {
+	return c.err
+}
+
+func TestReinstallCooldown(t *testing.T) {
+	resp := &slack.OAuthResponse{AccessToken: "xoxp-1", TeamID: "T1"}
+	resp.Bot.BotAccessToken = "xoxb-1"
+	store := &tokenStoreMock{}
+	auth := withConfig(&slackAuth{
+		auths:             make(chan AuthEvent, 2),
+		api:               &slackAPIStub{resp: resp},
+		store:             store,
+		reinstallCooldown: time.Minute,
+		cooldowns:         newMemoryCooldownStore(),
+	}, &config{
+		successTpl: template.Must(template.New("success").Parse(tplSuccess)),
+		errorTpl:   template.Must(template.New("error").Parse("{{.Error}}")),
+	})
+
+	w := httptest.NewRecorder()
+	auth.handler().ServeHTTP(w, httptest.NewRequest("GET", "/auth?code=foo", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	auth.handler().ServeHTTP(w, httptest.NewRequest("GET", "/auth?code=foo", nil))
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, ErrReinstallCooldown.Error(), w.Body.String())
+	assert.Len(t, auth.auths, 1)
+	assert.Equal(t, []*slack.OAuthResponse{resp}, store.saved)
+	assert.Equal(t, "xoxb-1", store.saved[0].Bot.BotAccessToken)
+
+	var handled error
+	auth.OnError(func(err error, r *http.Request) { handled = err })
+	auth.handler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/auth?code=foo", nil))
+	assert.True(t, errors.Is(handled, ErrReinstallCooldown))
+
+	withConfig(auth, &config{
+		successTpl:  template.Must(template.New("success").Parse(tplSuccess)),
+		errorTpl:    template.Must(template.New("error").Parse(tplError)),
+		cooldownTpl: template.Must(template.New("cooldown").Parse("try later")),
+	})
+	w = httptest.NewRecorder()
+	auth.handler().ServeHTTP(w, httptest.NewRequest("GET", "/auth?code=foo", nil))
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "try later", w.Body.String())
+}
+
+func TestReinstallCooldownSaveError(t *testing.T) {
+	store := &tokenStoreMock{saveErr: errors.New("unreachable")}
+	auth := withConfig(&slackAuth{
+		auths:             make(chan AuthEvent, 2),
+		api:               &slackAPIStub{resp: &slack.OAuthResponse{AccessToken: "xoxp-1", TeamID: "T1"}},
+		store:             store,
+		reinstallCooldown: time.Minute,
+		cooldowns:         newMemoryCooldownStore(),
+	}, &config{
+		successTpl: template.Must(template.New("success").Parse(tplSuccess)),
+		errorTpl:   template.Must(template.New("error").Parse("{{.Error}}")),
+	})
+
+	w := httptest.NewRecorder()
+	auth.handler().ServeHTTP(w, httptest.NewRequest("GET", "/auth?code=foo", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	store.saveErr = nil
+	w = httptest.NewRecorder()
+	auth.handler().ServeHTTP(w, httptest.NewRequest("GET", "/auth?code=foo", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, store.saved, 1)
+}
+
+func TestCheckCooldown(t *testing.T) {
+	auth := &slackAuth{}
+	resp := &slack.OAuthResponse{AccessToken: "xoxb-1", TeamID: "T1"}
+	resp.Bot.BotAccessToken = "xoxb-1"
+	assert.Nil(t, auth.checkCooldown(context.Background(), resp))
+
+	auth.reinstallCooldown = time.Minute
+	auth.cooldowns = cooldownStoreStub{err: errors.New("unreachable")}
+	assert.Nil(t, auth.checkCooldown(context.Background(), resp))
+
+	auth.cooldowns = cooldownStoreStub{ok: false}
+	err := auth.checkCooldown(context.Background(), resp)
+	assert.True(t, errors.Is(err, ErrReinstallCooldown))
+	assert.EqualError(t, err, ErrReinstallCooldown.Error()+`: "T1"`)
+
+	assert.Nil(t, auth.checkCooldown(context.Background(), &slack.OAuthResponse{}))
+}
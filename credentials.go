@@ -0,0 +1,74 @@
+package slackauth
+
+import (
+	"sync"
+	"time"
+
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+// defaultCredentialsTTL is the time the credentials returned by a CredentialsProvider are
+// cached by default.
+const defaultCredentialsTTL = time.Minute
+
+// CredentialsProvider returns the client ID and client secret of the Slack app.
+type CredentialsProvider func() (clientID, clientSecret string, err error)
+
+// credentialsCache caches the credentials returned by a provider for a while, so the provider
+// is not called on every request.
+type credentialsCache struct {
+	provider CredentialsProvider
+	ttl      time.Duration
+	now      func() time.Time
+
+	mut     sync.Mutex
+	id      string
+	secret  string
+	expires time.Time
+}
+
+func newCredentialsCache(provider CredentialsProvider, ttl time.Duration) *credentialsCache {
+	if ttl <= 0 {
+		ttl = defaultCredentialsTTL
+	}
+
+	return &credentialsCache{provider: provider, ttl: ttl, now: time.Now}
+}
+
+func (c *credentialsCache) get() (string, string, error) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	now := c.now()
+	if now.Before(c.expires) {
+		return c.id, c.secret, nil
+	}
+
+	id, secret, err := c.provider()
+	if err != nil {
+		return "", "", err
+	}
+
+	c.id, c.secret, c.expires = id, secret, now.Add(c.ttl)
+	return id, secret, nil
+}
+
+// credentials returns the client ID and secret to use, either from the credentials provider or
+// the static options.
+func (s *slackAuth) credentials() (string, string, error) {
+	if s.creds == nil {
+		return s.clientID, s.clientSecret, nil
+	}
+	return s.creds.get()
+}
+
+// currentClientID returns the client ID to use. If the credentials provider fails, the static
+// client ID is returned.
+func (s *slackAuth) currentClientID() string {
+	id, _, err := s.credentials()
+	if err != nil {
+		log15.Error("error getting credentials", "step", "get_credentials", "err", err.Error())
+		return s.clientID
+	}
+	return id
+}
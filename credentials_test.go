@@ -0,0 +1,55 @@
+package slackauth
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCredentialsCache(t *testing.T) {
+	var calls int
+	cache := newCredentialsCache(func() (string, string, error) {
+		calls++
+		if calls == 3 {
+			return "", "", errors.New("secrets manager is down")
+		}
+		return "id", "secret", nil
+	}, time.Minute)
+
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		id, secret, err := cache.get()
+		assert.Nil(t, err)
+		assert.Equal(t, "id", id)
+		assert.Equal(t, "secret", secret)
+	}
+	assert.Equal(t, 1, calls)
+
+	now = now.Add(2 * time.Minute)
+	_, _, err := cache.get()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, calls)
+
+	now = now.Add(2 * time.Minute)
+	_, _, err = cache.get()
+	assert.NotNil(t, err)
+}
+
+func TestCurrentClientID(t *testing.T) {
+	auth := &slackAuth{clientID: "static"}
+	assert.Equal(t, "static", auth.currentClientID())
+
+	auth.creds = newCredentialsCache(func() (string, string, error) {
+		return "rotated", "secret", nil
+	}, 0)
+	assert.Equal(t, "rotated", auth.currentClientID())
+
+	auth.creds = newCredentialsCache(func() (string, string, error) {
+		return "", "", errors.New("secrets manager is down")
+	}, 0)
+	assert.Equal(t, "static", auth.currentClientID())
+}
@@ -0,0 +1,44 @@
+package slackauth
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+var (
+	// secretHeaderName matches the names of headers whose values must never be logged.
+	secretHeaderName = regexp.MustCompile(`(?i)authorization|cookie|token|secret|key|signature|password`)
+	// secretHeaderValue matches header values that look like Slack tokens or credentials.
+	secretHeaderValue = regexp.MustCompile(`(?i)xox[a-z]-[\w-]+|bearer\s+\S+|basic\s+\S+`)
+)
+
+// redactHeader returns the value of the given header with anything resembling a secret
+// redacted.
+func redactHeader(name, value string) string {
+	if secretHeaderName.MatchString(name) {
+		return "[REDACTED]"
+	}
+	return secretHeaderValue.ReplaceAllString(value, "[REDACTED]")
+}
+
+// logDebugHeaders logs the values of the headers listed in DebugLogHeaders.
+func (s *slackAuth) logDebugHeaders(r *http.Request) {
+	if len(s.debugLogHeaders) == 0 {
+		return
+	}
+
+	ctx := []interface{}{"step", "debug_headers", "path", r.URL.Path}
+	for _, name := range s.debugLogHeaders {
+		var value string
+		if strings.EqualFold(name, "Host") {
+			value = r.Host
+		} else {
+			value = strings.Join(r.Header.Values(name), ", ")
+		}
+		ctx = append(ctx, strings.ToLower(name), redactHeader(name, value))
+	}
+	log15.Debug("request headers", ctx...)
+}
@@ -0,0 +1,14 @@
+package slackauth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactHeader(t *testing.T) {
+	assert.Equal(t, "https", redactHeader("X-Forwarded-Proto", "https"))
+	assert.Equal(t, "[REDACTED]", redactHeader("Authorization", "Bearer foo"))
+	assert.Equal(t, "[REDACTED]", redactHeader("X-Api-Key", "foo"))
+	assert.Equal(t, "token [REDACTED] sent", redactHeader("X-Debug", "token xoxb-123-abc sent"))
+}
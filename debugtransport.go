@@ -0,0 +1,62 @@
+package slackauth
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"regexp"
+
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+// secretFields matches the JSON fields of Slack responses that must never be logged.
+var secretFields = regexp.MustCompile(`"(access_token|bot_access_token|client_secret|url|configuration_url)"\s*:\s*"[^"]*"`)
+
+// redactSecrets replaces the values of the secret fields of the given JSON body.
+func redactSecrets(body []byte) []byte {
+	return secretFields.ReplaceAll(body, []byte(`"$1":"[REDACTED]"`))
+}
+
+// debugTransport logs the raw status and body of every response, with secrets redacted.
+type debugTransport struct {
+	next http.RoundTripper
+}
+
+func (t *debugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	log15.Debug(
+		"slack response",
+		"step", "slack_exchange",
+		"path", req.URL.Path,
+		"status", resp.StatusCode,
+		"body", string(redactSecrets(body)),
+	)
+	return resp, nil
+}
+
+// newDebugClient returns a copy of the given client, or the default one if it's nil, that
+// logs all the responses it gets.
+func newDebugClient(client *http.Client) *http.Client {
+	debugClient := &http.Client{}
+	if client != nil {
+		*debugClient = *client
+	}
+
+	next := debugClient.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	debugClient.Transport = &debugTransport{next: next}
+	return debugClient
+}
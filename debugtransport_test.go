@@ -0,0 +1,63 @@
+package slackauth
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactSecrets(t *testing.T) {
+	body := `{"ok":true,"access_token":"xoxp-1","bot":{"bot_access_token": "xoxb-1"},"incoming_webhook":{"url":"https://hooks.slack.com/x"},"team_id":"T1"}`
+	redacted := string(redactSecrets([]byte(body)))
+	assert.NotContains(t, redacted, "xoxp-1")
+	assert.NotContains(t, redacted, "xoxb-1")
+	assert.NotContains(t, redacted, "hooks.slack.com")
+	assert.Contains(t, redacted, `"team_id":"T1"`)
+}
+
+func TestDebugClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"ok":false,"error":"invalid_code"}`))
+	}))
+	defer srv.Close()
+
+	resp, err := newDebugClient(nil).Get(srv.URL + "/api/oauth.access")
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, `{"ok":false,"error":"invalid_code"}`, string(body))
+}
+
+func TestDebugExchangeClient(t *testing.T) {
+	tpl := filepath.Join(t.TempDir(), "tpl.html")
+	assert.Nil(t, os.WriteFile(tpl, []byte("foo"), 0600))
+
+	svc, err := New(Options{
+		Addr:         ":8080",
+		ClientID:     "id",
+		ClientSecret: "secret",
+		SuccessTpl:   tpl,
+		ErrorTpl:     tpl,
+		Debug:        true,
+	})
+	assert.Nil(t, err)
+
+	api := svc.(*slackAuth).api.(*slackAPIWrapper)
+	assert.Nil(t, api.client)
+	assert.NotNil(t, api.exchangeClient)
+
+	fakeSlack(t)
+	resp, err := api.GetOAuthResponse(context.Background(), "id", "secret", "foo", true)
+	assert.Nil(t, err)
+	assert.Equal(t, "xoxp-1", resp.AccessToken)
+}
@@ -0,0 +1,50 @@
+package slackauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/nlopes/slack"
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+// ErrDumpWithoutDebug is returned when DumpDir is set but Debug is not, because dumps contain
+// tokens.
+var ErrDumpWithoutDebug = errors.New("slackauth: dump dir can only be used in debug mode")
+
+const dumpTimeFormat = "20060102T150405.000000000Z"
+
+// dump writes the given OAuth response, tokens included, to a new file in the dump dir.
+func (s *slackAuth) dump(resp *slack.OAuthResponse) {
+	bytes, err := json.MarshalIndent(resp, "", "  ")
+	if err != nil {
+		log15.Error("error encoding oauth response dump", "step", "dump_response", "err", err.Error())
+		return
+	}
+
+	name := time.Now().UTC().Format(dumpTimeFormat) + "-" + resp.TeamID + ".json"
+	if err := os.WriteFile(filepath.Join(s.dumpDir, name), bytes, 0600); err != nil {
+		log15.Error("error writing oauth response dump", "step", "dump_response", "err", err.Error())
+	}
+}
+
+func (s *slackAuth) Replay(ctx context.Context, file string) error {
+	bytes, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	var resp slack.OAuthResponse
+	if err := json.Unmarshal(bytes, &resp); err != nil {
+		return err
+	}
+
+	event := AuthEvent{Response: &resp, Source: s.source}
+	s.dispatch(event)
+	s.deliverInstall(ctx, event)
+	return nil
+}
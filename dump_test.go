@@ -0,0 +1,48 @@
+package slackauth
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nlopes/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDumpAndReplay(t *testing.T) {
+	dir := t.TempDir()
+	auth := &slackAuth{
+		api:     &slackAPIStub{resp: &slack.OAuthResponse{AccessToken: "xoxp-1", TeamID: "T1"}},
+		dumpDir: dir,
+		source:  "dev",
+	}
+
+	_, err := auth.HandleCallback(context.Background(), "foo")
+	assert.Nil(t, err)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*-T1.json"))
+	assert.Nil(t, err)
+	assert.Len(t, files, 1)
+
+	var replayed AuthEvent
+	auth.OnAuthEvent(func(event AuthEvent) {
+		replayed = event
+	})
+	assert.Nil(t, auth.Replay(context.Background(), files[0]))
+	assert.Equal(t, "xoxp-1", replayed.Response.AccessToken)
+	assert.Equal(t, "T1", replayed.Response.TeamID)
+	assert.Equal(t, "dev", replayed.Source)
+
+	assert.NotNil(t, auth.Replay(context.Background(), filepath.Join(dir, "missing.json")))
+}
+
+func TestDumpWithoutDebug(t *testing.T) {
+	_, err := New(Options{
+		Addr:         ":8080",
+		ClientID:     "foo",
+		ClientSecret: "bar",
+		DumpDir:      os.TempDir(),
+	})
+	assert.Equal(t, ErrDumpWithoutDebug, err)
+}
@@ -0,0 +1,180 @@
+package slackauth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+const (
+	// errorWebhookAttempts is the number of times an install error is posted before giving up.
+	errorWebhookAttempts = 3
+	// errorWebhookTimeout is the maximum time a single post of an install error can take.
+	errorWebhookTimeout = 5 * time.Second
+	// defaultErrorWebhookBackoff is the time to wait before the first retry. It doubles on
+	// every retry.
+	defaultErrorWebhookBackoff = 500 * time.Millisecond
+	// errorWebhookQueueSize is the number of install errors waiting to be posted. Errors
+	// reported while the queue is full are dropped.
+	errorWebhookQueueSize = 64
+)
+
+// installError is the JSON body posted to the error webhook for every failed install.
+type installError struct {
+	Class     string    `json:"class"`
+	Message   string    `json:"message"`
+	TeamID    string    `json:"team_id,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// teamError is an error of the install of a known team, so it can be reported along with the
+// team ID.
+type teamError struct {
+	teamID string
+	err    error
+}
+
+func (e *teamError) Error() string {
+	return e.err.Error()
+}
+
+func (e *teamError) Unwrap() error {
+	return e.err
+}
+
+// withTeam returns the given error along with the ID of the team it happened to, if any.
+func withTeam(teamID string, err error) error {
+	if teamID == "" {
+		return err
+	}
+	return &teamError{teamID: teamID, err: err}
+}
+
+// errorTeamID returns the ID of the team the given error happened to, if known.
+func errorTeamID(err error) string {
+	var e *teamError
+	if errors.As(err, &e) {
+		return e.teamID
+	}
+	return ""
+}
+
+// errorQueue holds the install errors waiting to be posted by a single worker, which is
+// started with the first error and stopped by Stop.
+type errorQueue struct {
+	mut    sync.Mutex
+	closed bool
+	queue  chan installError
+}
+
+// reportError queues the given install error to be posted to the error webhook, if any, in the
+// background. Since failed installs can be triggered by anyone, the error is dropped if there
+// are too many waiting already.
+func (s *slackAuth) reportError(class, teamID string, err error) {
+	if s.errorWebhook == "" {
+		return
+	}
+
+	e := installError{
+		Class:     class,
+		Message:   secretHeaderValue.ReplaceAllString(err.Error(), "[REDACTED]"),
+		TeamID:    teamID,
+		Timestamp: time.Now().UTC(),
+	}
+
+	s.errorQueue.mut.Lock()
+	defer s.errorQueue.mut.Unlock()
+	if s.errorQueue.closed {
+		log15.Warn("install error dropped, the service is stopped", "step", "report_error", "class", class)
+		return
+	}
+	if s.errorQueue.queue == nil {
+		s.errorQueue.queue = make(chan installError, errorWebhookQueueSize)
+		go s.postErrors(s.errorQueue.queue)
+	}
+
+	select {
+	case s.errorQueue.queue <- e:
+	default:
+		log15.Warn("install error dropped, too many waiting to be posted", "step", "report_error", "class", class)
+	}
+}
+
+// closeErrors stops the error webhook worker once the queued install errors have been posted.
+// Errors reported afterwards are dropped.
+func (s *slackAuth) closeErrors() {
+	s.errorQueue.mut.Lock()
+	defer s.errorQueue.mut.Unlock()
+	if s.errorQueue.closed {
+		return
+	}
+	s.errorQueue.closed = true
+	if s.errorQueue.queue != nil {
+		close(s.errorQueue.queue)
+	}
+}
+
+// postErrors posts the install errors of the given queue one at a time.
+func (s *slackAuth) postErrors(queue <-chan installError) {
+	for e := range queue {
+		s.postError(e)
+	}
+}
+
+func (s *slackAuth) postError(e installError) {
+	body, err := json.Marshal(e)
+	if err != nil {
+		log15.Error("error encoding install error", "step", "report_error", "err", err.Error())
+		return
+	}
+
+	backoff := s.errorWebhookBackoff
+	if backoff <= 0 {
+		backoff = defaultErrorWebhookBackoff
+	}
+
+	for attempt := 1; ; attempt++ {
+		err = s.sendError(body)
+		if err == nil {
+			return
+		}
+
+		if attempt == errorWebhookAttempts {
+			log15.Error("error posting install error", "step", "report_error", "class", e.Class, "attempts", attempt, "err", err.Error())
+			return
+		}
+
+		log15.Warn("error posting install error, retrying", "step", "report_error", "class", e.Class, "attempt", attempt, "err", err.Error())
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func (s *slackAuth) sendError(body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), errorWebhookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.errorWebhook, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package slackauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nlopes/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorWebhook(t *testing.T) {
+	var calls int32
+	errs := make(chan installError, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+
+		var e installError
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&e))
+		errs <- e
+	}))
+	defer srv.Close()
+
+	auth := &slackAuth{errorWebhook: srv.URL, errorWebhookBackoff: time.Millisecond}
+	auth.reportError("process_install", "T1", errors.New("invalid token xoxb-123-abc"))
+
+	select {
+	case e := <-errs:
+		assert.Equal(t, "process_install", e.Class)
+		assert.Equal(t, "T1", e.TeamID)
+		assert.Equal(t, "invalid token [REDACTED]", e.Message)
+		assert.False(t, e.Timestamp.IsZero())
+	case <-time.After(time.Second):
+		t.Fatal("install error was not posted")
+	}
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestErrorWebhookGivesUp(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	auth := &slackAuth{errorWebhook: srv.URL, errorWebhookBackoff: time.Millisecond}
+	auth.postError(installError{Class: "slack_exchange", Message: "invalid_code"})
+	assert.Equal(t, int32(errorWebhookAttempts), atomic.LoadInt32(&calls))
+}
+
+func TestErrorWebhookQueue(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	auth := &slackAuth{errorWebhook: srv.URL, errorWebhookBackoff: time.Millisecond}
+	for i := 0; i < errorWebhookQueueSize*2; i++ {
+		auth.reportError("authorization_denied", "", errAuthorizationDenied)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	assert.True(t, len(auth.errorQueue.queue) <= errorWebhookQueueSize)
+}
+
+func TestErrorWebhookAuthorizationDenied(t *testing.T) {
+	errs := make(chan installError, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e installError
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&e))
+		errs <- e
+	}))
+	defer srv.Close()
+
+	auth := withConfig(&slackAuth{errorWebhook: srv.URL}, &config{
+		errorTpl: template.Must(template.New("error").Parse(tplError)),
+	})
+	w := httptest.NewRecorder()
+	auth.handler().ServeHTTP(w, httptest.NewRequest("GET", "/auth?error=attacker+controlled", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	select {
+	case e := <-errs:
+		assert.Equal(t, "authorization_denied", e.Class)
+		assert.Equal(t, "authorization denied", e.Message)
+	case <-time.After(time.Second):
+		t.Fatal("install error was not posted")
+	}
+}
+
+func TestErrorWebhookTeamID(t *testing.T) {
+	errs := make(chan installError, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e installError
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&e))
+		errs <- e
+	}))
+	defer srv.Close()
+
+	auth := withConfig(&slackAuth{
+		api:               &slackAPIStub{resp: &slack.OAuthResponse{TeamID: "T1"}},
+		errorWebhook:      srv.URL,
+		reinstallCooldown: time.Minute,
+		cooldowns:         cooldownStoreStub{ok: false},
+	}, &config{
+		errorTpl: template.Must(template.New("error").Parse(tplError)),
+	})
+	var handled error
+	auth.OnError(func(err error, r *http.Request) { handled = err })
+
+	w := httptest.NewRecorder()
+	auth.handler().ServeHTTP(w, httptest.NewRequest("GET", "/auth?code=foo", nil))
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.True(t, errors.Is(handled, ErrReinstallCooldown))
+
+	select {
+	case e := <-errs:
+		assert.Equal(t, "reinstall_cooldown", e.Class)
+		assert.Equal(t, "T1", e.TeamID)
+	case <-time.After(time.Second):
+		t.Fatal("install error was not posted")
+	}
+}
+
+func TestErrorWebhookStop(t *testing.T) {
+	errs := make(chan installError, 2)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e installError
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&e))
+		errs <- e
+	}))
+	defer srv.Close()
+
+	auth := &slackAuth{auths: make(chan AuthEvent), errorWebhook: srv.URL}
+	auth.reportError("slack_exchange", "", errors.New("invalid_code"))
+	assert.Nil(t, auth.Stop(context.Background()))
+	auth.reportError("slack_exchange", "", errors.New("invalid_code"))
+
+	select {
+	case e := <-errs:
+		assert.Equal(t, "slack_exchange", e.Class)
+	case <-time.After(time.Second):
+		t.Fatal("install error was not posted")
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, errs, 0)
+	assert.True(t, auth.errorQueue.closed)
+}
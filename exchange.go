@@ -0,0 +1,75 @@
+package slackauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/nlopes/slack"
+)
+
+// usesCustomExchange reports whether the exchange can't go through the Slack library, because
+// the credentials go in the header, there are extra params or it needs its own HTTP client.
+func (w *slackAPIWrapper) usesCustomExchange() bool {
+	return w.credentialsInHeader || len(w.exchangeParams) > 0 || w.exchangeClient != nil
+}
+
+// customExchange exchanges the code for an OAuth response like the Slack library does, but
+// sending the client ID and client secret as basic auth if credentialsInHeader is set, and the
+// extra exchange params in the body of the request.
+func (w *slackAPIWrapper) customExchange(ctx context.Context, id, secret, code string) (*slack.OAuthResponse, error) {
+	var response slack.OAuthResponse
+	if err := w.postExchange(ctx, "oauth.access", id, secret, code, &response); err != nil {
+		return nil, err
+	}
+
+	if !response.Ok {
+		return nil, errors.New(response.Error)
+	}
+	return &response, nil
+}
+
+// postExchange posts the code to the given OAuth method of the Slack API and decodes the
+// response into out.
+func (w *slackAPIWrapper) postExchange(ctx context.Context, method, id, secret, code string, out interface{}) error {
+	client := w.exchangeClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	values := url.Values{}
+	for k, v := range w.exchangeParams {
+		values.Set(k, v)
+	}
+	values.Set("code", code)
+	values.Set("redirect_uri", "")
+	if !w.credentialsInHeader {
+		values.Set("client_id", id)
+		values.Set("client_secret", secret)
+	}
+
+	req, err := http.NewRequest("POST", slack.SLACK_API+method, strings.NewReader(values.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if w.credentialsInHeader {
+		req.SetBasicAuth(id, secret)
+	}
+
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("slackauth: %s responded with %s", method, resp.Status)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
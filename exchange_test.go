@@ -0,0 +1,84 @@
+package slackauth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nlopes/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSlack starts a server answering oauth.access like Slack, with the client credentials
+// either in the body or in the header, and points the Slack library to it.
+func fakeSlack(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/oauth.access", r.URL.Path)
+		id, secret, ok := r.BasicAuth()
+		if !ok {
+			id, secret = r.PostFormValue("client_id"), r.PostFormValue("client_secret")
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if r.PostFormValue("grant_type") == "refresh_token" {
+			w.Write([]byte(`{"ok":true,"access_token":"xoxe-1"}`))
+			return
+		}
+		if id != "id" || secret != "secret" || r.PostFormValue("code") != "foo" {
+			w.Write([]byte(`{"ok":false,"error":"invalid_client_id"}`))
+			return
+		}
+		w.Write([]byte(`{"ok":true,"access_token":"xoxp-1","scope":"bot","team_id":"T1"}`))
+	}))
+
+	api := slack.SLACK_API
+	slack.SLACK_API = srv.URL + "/"
+	slack.SetHTTPClient(srv.Client())
+	t.Cleanup(func() {
+		slack.SLACK_API = api
+		slack.SetHTTPClient(http.DefaultClient)
+		srv.Close()
+	})
+}
+
+func TestCredentialsInBody(t *testing.T) {
+	fakeSlack(t)
+	api := &slackAPIWrapper{}
+
+	resp, err := api.GetOAuthResponse(context.Background(), "id", "secret", "foo", false)
+	assert.Nil(t, err)
+	assert.Equal(t, "xoxp-1", resp.AccessToken)
+	assert.Equal(t, "T1", resp.TeamID)
+
+	_, err = api.GetOAuthResponse(context.Background(), "id", "invalid", "foo", false)
+	assert.EqualError(t, err, "invalid_client_id")
+}
+
+func TestCredentialsInHeader(t *testing.T) {
+	fakeSlack(t)
+	api := &slackAPIWrapper{credentialsInHeader: true}
+
+	resp, err := api.GetOAuthResponse(context.Background(), "id", "secret", "foo", false)
+	assert.Nil(t, err)
+	assert.Equal(t, "xoxp-1", resp.AccessToken)
+	assert.Equal(t, "T1", resp.TeamID)
+
+	_, err = api.GetOAuthResponse(context.Background(), "id", "invalid", "foo", false)
+	assert.EqualError(t, err, "invalid_client_id")
+	assert.False(t, isTransient(err))
+}
+
+func TestExchangeParams(t *testing.T) {
+	fakeSlack(t)
+	api := &slackAPIWrapper{exchangeParams: map[string]string{"grant_type": "refresh_token", "code": "bar"}}
+
+	resp, err := api.GetOAuthResponse(context.Background(), "id", "secret", "foo", false)
+	assert.Nil(t, err)
+	assert.Equal(t, "xoxe-1", resp.AccessToken)
+
+	api.exchangeParams = map[string]string{"code": "bar", "client_secret": "invalid"}
+	resp, err = api.GetOAuthResponse(context.Background(), "id", "secret", "foo", false)
+	assert.Nil(t, err)
+	assert.Equal(t, "xoxp-1", resp.AccessToken)
+}
@@ -0,0 +1,51 @@
+package slackauth
+
+import (
+	"sync"
+	"time"
+)
+
+// defaultFeatureFlagsTTL is the time the flags returned by Options.FeatureFlags are cached by
+// default.
+const defaultFeatureFlagsTTL = 10 * time.Second
+
+// featureFlagsCache caches the flags returned by a provider for a while, so the provider is
+// not called on every button render.
+type featureFlagsCache struct {
+	provider func() map[string]bool
+	ttl      time.Duration
+	now      func() time.Time
+
+	mut     sync.Mutex
+	flags   map[string]bool
+	expires time.Time
+}
+
+func newFeatureFlagsCache(provider func() map[string]bool, ttl time.Duration) *featureFlagsCache {
+	if ttl <= 0 {
+		ttl = defaultFeatureFlagsTTL
+	}
+
+	return &featureFlagsCache{provider: provider, ttl: ttl, now: time.Now}
+}
+
+func (c *featureFlagsCache) get() map[string]bool {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	now := c.now()
+	if now.Before(c.expires) {
+		return c.flags
+	}
+
+	c.flags, c.expires = c.provider(), now.Add(c.ttl)
+	return c.flags
+}
+
+// featureFlags returns the current feature flags, or nil if there is no provider.
+func (s *slackAuth) featureFlags() map[string]bool {
+	if s.flags == nil {
+		return nil
+	}
+	return s.flags.get()
+}
@@ -0,0 +1,48 @@
+package slackauth
+
+import (
+	"html/template"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFeatureFlagsCache(t *testing.T) {
+	var calls int
+	cache := newFeatureFlagsCache(func() map[string]bool {
+		calls++
+		return map[string]bool{"beta": calls > 1}
+	}, time.Minute)
+
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, map[string]bool{"beta": false}, cache.get())
+	}
+	assert.Equal(t, 1, calls)
+
+	now = now.Add(2 * time.Minute)
+	assert.Equal(t, map[string]bool{"beta": true}, cache.get())
+	assert.Equal(t, 2, calls)
+}
+
+func TestFeatureFlagsButton(t *testing.T) {
+	tpl := `{{if .Flags.beta}}beta{{else}}stable{{end}}`
+	auth := withConfig(&slackAuth{}, &config{
+		buttonTpl: template.Must(template.New("button").Parse(tpl)),
+	})
+
+	w := httptest.NewRecorder()
+	auth.handler().ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, "stable", w.Body.String())
+
+	auth.flags = newFeatureFlagsCache(func() map[string]bool {
+		return map[string]bool{"beta": true}
+	}, 0)
+	w = httptest.NewRecorder()
+	auth.handler().ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, "beta", w.Body.String())
+}
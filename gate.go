@@ -0,0 +1,68 @@
+package slackauth
+
+import (
+	"io"
+	"net/http"
+
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+func (s *slackAuth) OnBeforeButton(fn func(*http.Request) (bool, error)) {
+	s.handlersMut.Lock()
+	s.beforeButtonHandler = fn
+	s.handlersMut.Unlock()
+}
+
+// gateButton runs the before button handler, if any, and reports whether it prevented the
+// button from being displayed or the install from starting, in which case the response has
+// already been written.
+func (s *slackAuth) gateButton(w http.ResponseWriter, r *http.Request) bool {
+	s.handlersMut.RLock()
+	handler := s.beforeButtonHandler
+	s.handlersMut.RUnlock()
+
+	if handler == nil {
+		return false
+	}
+
+	cfg := s.config()
+	ok, err := handler(r)
+	if err != nil {
+		log15.Error("error running before button handler", "step", "before_button", "err", err.Error())
+		s.handleError(err, r)
+		cfg.renderError(w, http.StatusInternalServerError, err.Error())
+		return true
+	}
+
+	if ok {
+		return false
+	}
+
+	if s.buttonAuthRedirect != nil {
+		http.Redirect(w, r, s.loginURL(r), http.StatusFound)
+		return true
+	}
+
+	if cfg.gateTpl != nil {
+		err := cfg.render(w, http.StatusForbidden, "", cfg.gateTpl, nil)
+		if err == nil {
+			return true
+		}
+		log15.Error("error displaying gate tpl", "step", "render_gate", "err", err.Error())
+	}
+
+	w.WriteHeader(http.StatusForbidden)
+	io.WriteString(w, "You are not allowed to install this app.")
+	return true
+}
+
+// loginURL returns the button auth redirect URL with the path of the given request as the
+// return_to param, so the user can get back to the install page after logging in. The path
+// is relative, since the host of the request is set by the client.
+func (s *slackAuth) loginURL(r *http.Request) string {
+	u := *s.buttonAuthRedirect
+	q := u.Query()
+	q.Set("return_to", r.URL.RequestURI())
+	u.RawQuery = q.Encode()
+	return u.String()
+}
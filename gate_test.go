@@ -0,0 +1,119 @@
+package slackauth
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOnBeforeButton(t *testing.T) {
+	auth := withConfig(&slackAuth{}, &config{
+		buttonTpl: template.Must(template.New("button").Parse("button")),
+		errorTpl:  template.Must(template.New("error").Parse("error: {{.Error}}")),
+		gateTpl:   template.Must(template.New("gate").Parse("login required")),
+	})
+	handler := auth.handler()
+
+	var allowed bool
+	var err error
+	auth.OnBeforeButton(func(r *http.Request) (bool, error) {
+		return allowed, err
+	})
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, "login required", w.Body.String())
+
+	allowed = true
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "button", w.Body.String())
+
+	err = errors.New("session store is down")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, "error: session store is down", w.Body.String())
+}
+
+func TestOnBeforeButtonRoutes(t *testing.T) {
+	auth := withConfig(&slackAuth{
+		clientID:         "foo",
+		authorizeBaseURL: DefaultAuthorizeBaseURL,
+		scopeSetParam:    defaultScopeSetParam,
+		optionalBot:      true,
+		consent:          true,
+	}, &config{
+		buttonTpl:  template.Must(template.New("button").Parse("button")),
+		consentTpl: template.Must(template.New("consent").Parse("terms")),
+		errorTpl:   template.Must(template.New("error").Parse("error: {{.Error}}")),
+		gateTpl:    template.Must(template.New("gate").Parse("login required")),
+		scopes:     "bot,commands",
+	})
+	handler := auth.handler()
+
+	var err error
+	auth.OnBeforeButton(func(r *http.Request) (bool, error) {
+		return false, err
+	})
+	var handled error
+	auth.OnError(func(err error, r *http.Request) { handled = err })
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/install?include_bot=true", nil))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, "login required", w.Body.String())
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("POST", "/consent", nil))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, "login required", w.Body.String())
+
+	err = errors.New("session store is down")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/install", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, err, handled)
+}
+
+func TestButtonAuthRedirect(t *testing.T) {
+	login, err := url.Parse("https://example.com/login?app=slack")
+	assert.Nil(t, err)
+	auth := withConfig(&slackAuth{buttonAuthRedirect: login}, &config{
+		buttonTpl: template.Must(template.New("button").Parse("button")),
+		errorTpl:  template.Must(template.New("error").Parse("error: {{.Error}}")),
+	})
+	auth.OnBeforeButton(func(r *http.Request) (bool, error) {
+		return r.Header.Get("Cookie") != "", nil
+	})
+	handler := auth.handler()
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "http://install.example.com/?set=pro", nil))
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t,
+		"https://example.com/login?app=slack&return_to=%2F%3Fset%3Dpro",
+		w.Header().Get("Location"),
+	)
+
+	// The host of the request is not trusted.
+	r := httptest.NewRequest("GET", "/?set=pro", nil)
+	r.Host = "evil.example.com"
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.NotContains(t, w.Header().Get("Location"), "evil")
+
+	r = httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Cookie", "session=foo")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "button", w.Body.String())
+}
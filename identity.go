@@ -0,0 +1,99 @@
+package slackauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/nlopes/slack"
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+// Identity is the identity of the user who authorized the app. It's only available when
+// identity scopes were granted.
+type Identity struct {
+	// UserID is the ID of the user.
+	UserID string
+	// RealName is the name of the user.
+	RealName string
+	// Email is the email of the user. It's only available with the identity.email scope.
+	Email string
+}
+
+// grantedScopes returns the scopes granted in the given OAuth response.
+func grantedScopes(resp *slack.OAuthResponse) []string {
+	if resp.Scope == "" {
+		return nil
+	}
+	return strings.Split(resp.Scope, ",")
+}
+
+// identityScopeNames are the scopes of the Sign in with Slack flow.
+var identityScopeNames = map[string]bool{
+	"identity.basic":  true,
+	"identity.email":  true,
+	"identity.avatar": true,
+	"identity.team":   true,
+}
+
+// ErrInvalidIdentityScope is returned when one of the identity scopes is not a Sign in with
+// Slack scope.
+var ErrInvalidIdentityScope = errors.New("slackauth: invalid identity scope")
+
+// validateIdentityScopes checks all the given scopes are identity scopes, including
+// identity.basic, which Slack requires to request any of the others.
+func validateIdentityScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return nil
+	}
+
+	var basic bool
+	for _, scope := range scopes {
+		if !identityScopeNames[scope] {
+			return fmt.Errorf("%w: %q", ErrInvalidIdentityScope, scope)
+		}
+		basic = basic || scope == "identity.basic"
+	}
+
+	if !basic {
+		return fmt.Errorf("%w: identity.basic is required", ErrInvalidIdentityScope)
+	}
+	return nil
+}
+
+func (s *slackAuth) SignInURL() string {
+	if len(s.identityScopes) == 0 {
+		return ""
+	}
+	return s.authorizeURL(s.identityScopes)
+}
+
+func hasIdentityScope(resp *slack.OAuthResponse) bool {
+	for _, scope := range grantedScopes(resp) {
+		if strings.HasPrefix(scope, "identity.") {
+			return true
+		}
+	}
+	return false
+}
+
+// identity returns the identity of the user who authorized the app, if identity scopes were
+// granted. Failing to get it does not fail the install, the identity is just left empty.
+func (s *slackAuth) identity(ctx context.Context, resp *slack.OAuthResponse) Identity {
+	if !hasIdentityScope(resp) {
+		return Identity{}
+	}
+
+	identity, err := s.api.GetUserIdentity(ctx, resp.AccessToken)
+	if err != nil {
+		log15.Error("error getting user identity", "step", "get_identity", "team id", resp.TeamID, "err", err.Error())
+		return Identity{}
+	}
+
+	return Identity{
+		UserID:   identity.User.ID,
+		RealName: identity.User.Name,
+		Email:    identity.User.Email,
+	}
+}
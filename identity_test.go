@@ -0,0 +1,98 @@
+package slackauth
+
+import (
+	"context"
+	"html/template"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nlopes/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdentity(t *testing.T) {
+	auth := withConfig(&slackAuth{
+		auths: make(chan AuthEvent, 2),
+		api:   &slackAPIStub{resp: &slack.OAuthResponse{AccessToken: "foo", Scope: "identity.basic,identity.email"}},
+	}, &config{
+		successTpl: template.Must(template.New("success").Parse("{{.RealName}} {{.Email}}")),
+		errorTpl:   template.Must(template.New("error").Parse(tplError)),
+	})
+
+	w := httptest.NewRecorder()
+	auth.handler().ServeHTTP(w, httptest.NewRequest("GET", "/auth?code=foo", nil))
+	assert.Equal(t, "Jane Doe jane@example.com", w.Body.String())
+
+	event := <-auth.auths
+	assert.Equal(t, Identity{UserID: "U1", RealName: "Jane Doe", Email: "jane@example.com"}, event.Identity)
+}
+
+func TestIdentityWithoutScope(t *testing.T) {
+	auth := withConfig(&slackAuth{
+		auths: make(chan AuthEvent, 2),
+		api:   &slackAPIStub{resp: &slack.OAuthResponse{AccessToken: "foo", Scope: "bot"}},
+	}, &config{
+		successTpl: template.Must(template.New("success").Parse("{{.Email}}")),
+		errorTpl:   template.Must(template.New("error").Parse(tplError)),
+	})
+
+	w := httptest.NewRecorder()
+	auth.handler().ServeHTTP(w, httptest.NewRequest("GET", "/auth?code=foo", nil))
+	assert.Equal(t, "", w.Body.String())
+	assert.Equal(t, Identity{}, (<-auth.auths).Identity)
+}
+
+func TestIdentityError(t *testing.T) {
+	auth := withConfig(&slackAuth{
+		auths: make(chan AuthEvent, 2),
+		api:   &slackAPIStub{resp: &slack.OAuthResponse{AccessToken: "bar", Scope: "identity.email"}},
+	}, &config{
+		successTpl: template.Must(template.New("success").Parse("ok{{.Email}}")),
+		errorTpl:   template.Must(template.New("error").Parse(tplError)),
+	})
+
+	w := httptest.NewRecorder()
+	auth.handler().ServeHTTP(w, httptest.NewRequest("GET", "/auth?code=foo", nil))
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "ok", w.Body.String())
+}
+
+func TestValidateIdentityScopes(t *testing.T) {
+	assert.Nil(t, validateIdentityScopes(nil))
+	assert.Nil(t, validateIdentityScopes([]string{"identity.basic", "identity.email"}))
+	assert.ErrorIs(t, validateIdentityScopes([]string{"identity.basic", BOT}), ErrInvalidIdentityScope)
+	assert.ErrorIs(t, validateIdentityScopes([]string{"identity.email"}), ErrInvalidIdentityScope)
+}
+
+func TestSignInURL(t *testing.T) {
+	auth := withConfig(&slackAuth{
+		clientID:         "foo",
+		authorizeBaseURL: DefaultAuthorizeBaseURL,
+	}, &config{
+		buttonTpl: template.Must(template.New("button").Parse("{{.SignInURL}}")),
+		scopes:    "bot",
+	})
+	assert.Equal(t, "", auth.SignInURL())
+
+	auth.identityScopes = []string{"identity.basic", "identity.email"}
+	expected := "https://slack.com/oauth/authorize?client_id=foo&scope=identity.basic%2Cidentity.email"
+	assert.Equal(t, expected, auth.SignInURL())
+	assert.Equal(t, "https://slack.com/oauth/authorize?client_id=foo&scope=bot", auth.AuthorizeURL())
+
+	w := httptest.NewRecorder()
+	auth.handler().ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, template.HTMLEscapeString(expected), w.Body.String())
+}
+
+func TestSignInGrantedScopes(t *testing.T) {
+	auth := &slackAuth{
+		api: &slackAPIStub{resp: &slack.OAuthResponse{AccessToken: "foo", Scope: "identity.basic"}},
+	}
+	_, err := auth.exchange(context.Background(), "foo", []string{BOT})
+	assert.NotNil(t, err)
+
+	auth.identityScopes = []string{"identity.basic"}
+	event, err := auth.exchange(context.Background(), "foo", []string{BOT})
+	assert.Nil(t, err)
+	assert.Equal(t, "U1", event.Identity.UserID)
+}
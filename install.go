@@ -0,0 +1,67 @@
+package slackauth
+
+import (
+	"context"
+	"sync"
+
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+// Install is a successful authorization waiting to be processed by the consumer of the
+// Installs channel.
+//
+// Done must be called exactly once, when the consumer has finished processing the install,
+// with the error that happened during the processing, if any. When a TokenStore is configured,
+// the OAuth response is only saved after Done is called with a nil error.
+type Install struct {
+	AuthEvent
+	// Done signals the processing of the install has finished. Calls after the first one
+	// are ignored.
+	Done func(error)
+}
+
+func (s *slackAuth) Installs() <-chan *Install {
+	return s.installs
+}
+
+// newInstall returns an Install for the given event, whose Done function completes the install.
+func (s *slackAuth) newInstall(event AuthEvent) *Install {
+	var once sync.Once
+	return &Install{
+		AuthEvent: event,
+		Done: func(err error) {
+			once.Do(func() {
+				s.completeInstall(event, err)
+			})
+		},
+	}
+}
+
+func (s *slackAuth) completeInstall(event AuthEvent, err error) {
+	if err != nil {
+		log15.Error("error processing install", "step", "process_install", "team id", event.Response.TeamID, "err", err.Error())
+		s.reportError("process_install", event.Response.TeamID, err)
+		return
+	}
+
+	if s.store == nil {
+		return
+	}
+
+	if err := s.store.Save(context.Background(), event.Response); err != nil {
+		log15.Error("error saving oauth response", "step", "save_token", "err", err.Error())
+	}
+}
+
+// deliverInstall sends the event to the Installs channel, if it's enabled.
+func (s *slackAuth) deliverInstall(ctx context.Context, event AuthEvent) {
+	if s.installs == nil {
+		return
+	}
+
+	select {
+	case s.installs <- s.newInstall(event):
+	case <-ctx.Done():
+		log15.Error("install was not delivered", "step", "deliver_install", "team id", event.Response.TeamID, "err", ctx.Err().Error())
+	}
+}
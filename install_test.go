@@ -0,0 +1,34 @@
+package slackauth
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstalls(t *testing.T) {
+	store := &tokenStoreMock{}
+	auth := &slackAuth{
+		api:      &slackAPIMock{},
+		store:    store,
+		installs: make(chan *Install, 2),
+	}
+
+	_, err := auth.HandleCallback(context.Background(), "foo")
+	assert.Nil(t, err)
+	_, err = auth.HandleCallback(context.Background(), "bar")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(store.saved))
+
+	install := <-auth.Installs()
+	install.Done(errors.New("provisioning failed"))
+	assert.Equal(t, 0, len(store.saved))
+
+	install = <-auth.Installs()
+	install.Done(nil)
+	assert.Equal(t, 1, len(store.saved))
+	install.Done(nil)
+	assert.Equal(t, 1, len(store.saved))
+}
@@ -0,0 +1,20 @@
+package slackauth
+
+import (
+	"context"
+	"net"
+)
+
+// listen creates the listener of the server on the Unix socket or the address, using the
+// configured listen config, if any.
+func (s *slackAuth) listen() (net.Listener, error) {
+	lc := s.listenConfig
+	if lc == nil {
+		lc = &net.ListenConfig{}
+	}
+
+	if s.unixSocket != "" {
+		return listenUnix(lc, s.unixSocket)
+	}
+	return lc.Listen(context.Background(), "tcp", s.addr)
+}
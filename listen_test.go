@@ -0,0 +1,34 @@
+package slackauth
+
+import (
+	"net"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListenConfig(t *testing.T) {
+	var networks []string
+	auth := &slackAuth{
+		addr: "127.0.0.1:0",
+		listenConfig: &net.ListenConfig{
+			Control: func(network, address string, c syscall.RawConn) error {
+				networks = append(networks, network)
+				return nil
+			},
+		},
+	}
+
+	ln, err := auth.listen()
+	assert.Nil(t, err)
+	assert.Nil(t, ln.Close())
+
+	auth.unixSocket = filepath.Join(t.TempDir(), "slackauth.sock")
+	ln, err = auth.listen()
+	assert.Nil(t, err)
+	assert.Nil(t, ln.Close())
+
+	assert.Equal(t, []string{"tcp4", "unix"}, networks)
+}
@@ -0,0 +1,42 @@
+package slackauth
+
+import (
+	"io"
+	"os"
+	"sync"
+)
+
+// logOutput is the writer logs are written to. The underlying writer can be swapped while
+// logs are being written.
+type logOutput struct {
+	mut sync.Mutex
+	w   io.Writer
+}
+
+func (o *logOutput) Write(p []byte) (int, error) {
+	o.mut.Lock()
+	defer o.mut.Unlock()
+	return o.w.Write(p)
+}
+
+// swap replaces the underlying writer and returns the previous one, if any. Once it returns,
+// nothing else is written to the previous writer.
+func (o *logOutput) swap(w io.Writer) io.Writer {
+	o.mut.Lock()
+	defer o.mut.Unlock()
+	prev := o.w
+	o.w = w
+	return prev
+}
+
+func (s *slackAuth) SwapLogOutput(w io.Writer) io.Writer {
+	if w == nil {
+		w = os.Stdout
+	}
+
+	prev := s.logOut.swap(w)
+	if prev == nil {
+		s.SetLogOutput(w)
+	}
+	return prev
+}
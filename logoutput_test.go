@@ -0,0 +1,63 @@
+package slackauth
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+// syncBuffer is a buffer that can be written from several goroutines.
+type syncBuffer struct {
+	mut sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	return b.buf.String()
+}
+
+func TestSwapLogOutput(t *testing.T) {
+	handler := log15.Root().GetHandler()
+	defer log15.Root().SetHandler(handler)
+
+	first, second := &syncBuffer{}, &syncBuffer{}
+	auth := &slackAuth{}
+	assert.Nil(t, auth.SwapLogOutput(first))
+	log15.Info("before", "step", "test")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				log15.Info("during", "step", "test")
+			}
+		}()
+	}
+
+	assert.Equal(t, first, auth.SwapLogOutput(second))
+	written := first.String()
+	log15.Info("after", "step", "test")
+	wg.Wait()
+
+	assert.Equal(t, written, first.String())
+	assert.Contains(t, written, "msg=before")
+	assert.Contains(t, second.String(), "msg=after")
+	// Other tests may leave goroutines logging in the background, so only the lines of this
+	// one are counted.
+	lines := strings.Count(first.String(), "step=test") + strings.Count(second.String(), "step=test")
+	assert.Equal(t, 202, lines)
+}
@@ -0,0 +1,40 @@
+package slackauth
+
+import (
+	"encoding/json"
+	"net/http"
+
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+// manifestPath is the route where the install manifest is served.
+const manifestPath = "/.well-known/slack-app.json"
+
+// manifest is a machine-readable description of the install endpoint. It never contains the
+// client secret.
+type manifest struct {
+	ClientID     string   `json:"client_id"`
+	Scopes       []string `json:"scopes"`
+	AuthorizeURL string   `json:"authorize_url"`
+	RedirectURL  string   `json:"redirect_url"`
+}
+
+func (s *slackAuth) manifestHandler(w http.ResponseWriter, r *http.Request) {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	cfg := s.config()
+	m := manifest{
+		ClientID:     s.currentClientID(),
+		Scopes:       cfg.configuredScopes(),
+		AuthorizeURL: s.configuredAuthorizeURL(cfg),
+		RedirectURL:  scheme + "://" + r.Host + "/auth",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(m); err != nil {
+		log15.Error("error writing manifest", "step", "render_manifest", "err", err.Error())
+	}
+}
@@ -0,0 +1,34 @@
+package slackauth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManifest(t *testing.T) {
+	auth := withConfig(&slackAuth{
+		clientID:         "foo",
+		clientSecret:     "bar",
+		authorizeBaseURL: DefaultAuthorizeBaseURL,
+		serveManifest:    true,
+	}, &config{scopes: "bot,commands"})
+
+	w := httptest.NewRecorder()
+	auth.handler().ServeHTTP(w, httptest.NewRequest("GET", "http://example.com"+manifestPath, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.NotContains(t, w.Body.String(), "bar")
+
+	var m manifest
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &m))
+	assert.Equal(t, manifest{
+		ClientID:     "foo",
+		Scopes:       []string{BOT, COMMANDS},
+		AuthorizeURL: "https://slack.com/oauth/authorize?client_id=foo&scope=bot%2Ccommands",
+		RedirectURL:  "http://example.com/auth",
+	}, m)
+}
@@ -0,0 +1,21 @@
+package slackauth
+
+import (
+	"net/http"
+	"strings"
+)
+
+// allow answers OPTIONS requests with the given methods in the Allow header, without calling
+// the handler.
+func allow(h http.HandlerFunc, methods ...string) http.HandlerFunc {
+	allowed := strings.Join(append(methods, http.MethodOptions), ", ")
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodOptions {
+			h(w, r)
+			return
+		}
+
+		w.Header().Set("Allow", allowed)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
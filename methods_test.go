@@ -0,0 +1,35 @@
+package slackauth
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOptions(t *testing.T) {
+	auth := withConfig(&slackAuth{
+		auths: make(chan AuthEvent, 1),
+		api:   &slackAPIMock{},
+	}, &config{
+		successTpl: template.Must(template.New("success").Parse(`{{template "missing"}}`)),
+		errorTpl:   template.Must(template.New("error").Parse(`{{template "missing"}}`)),
+		buttonTpl:  template.Must(template.New("button").Parse(`{{template "missing"}}`)),
+	})
+	handler := auth.handler()
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("OPTIONS", "/", nil))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "GET, HEAD, OPTIONS", w.Header().Get("Allow"))
+	assert.Equal(t, "", w.Body.String())
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("OPTIONS", "/auth?code=foo", nil))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "GET, OPTIONS", w.Header().Get("Allow"))
+	assert.Equal(t, "", w.Body.String())
+	assert.Len(t, auth.auths, 0)
+}
@@ -0,0 +1,114 @@
+package slackauth
+
+import (
+	"sync"
+	"time"
+)
+
+// Metrics receives the metrics of the service, so they can be sent to any backend. Tags are
+// given as key:value strings.
+type Metrics interface {
+	// IncrCounter increments the counter with the given name by one.
+	IncrCounter(name string, tags ...string)
+	// ObserveDuration records the given duration in the histogram or timer with the given
+	// name.
+	ObserveDuration(name string, d time.Duration, tags ...string)
+}
+
+// Names of the metrics reported by the service.
+const (
+	// MetricButtonViews counts the button pages rendered.
+	MetricButtonViews = "slackauth.button_views"
+	// MetricInstalls counts the successful authorizations.
+	MetricInstalls = "slackauth.installs"
+	// MetricInstallErrors counts the failed authorizations, tagged with the step that failed.
+	MetricInstallErrors = "slackauth.install_errors"
+	// MetricExchangeDuration is the duration of the code exchanges with Slack, tagged with
+	// the API used, primary or fallback.
+	MetricExchangeDuration = "slackauth.exchange_duration"
+	// MetricRateLimited counts the requests rejected by the rate limit.
+	MetricRateLimited = "slackauth.rate_limited"
+	// MetricGrantedScopes counts the successful authorizations that granted each of the
+	// configured scopes, tagged with the scope.
+	MetricGrantedScopes = "slackauth.granted_scopes"
+	// MetricCallbackPanics counts the panics in auth handlers.
+	MetricCallbackPanics = "slackauth.callback_panics"
+)
+
+// noopMetrics discards all the metrics. It's used when no Metrics are configured.
+type noopMetrics struct{}
+
+func (noopMetrics) IncrCounter(string, ...string)                    {}
+func (noopMetrics) ObserveDuration(string, time.Duration, ...string) {}
+
+// metricCounters keeps the totals of the metrics reported by the service, regardless of
+// their tags, for MetricsSnapshot.
+type metricCounters struct {
+	mut    sync.Mutex
+	values map[string]float64
+}
+
+func (c *metricCounters) add(name string, n float64) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	if c.values == nil {
+		c.values = make(map[string]float64)
+	}
+	c.values[name] += n
+}
+
+// countingMetrics records the metrics in the service counters before sending them to the
+// configured metrics.
+type countingMetrics struct {
+	counters *metricCounters
+	sink     Metrics
+}
+
+func (m countingMetrics) IncrCounter(name string, tags ...string) {
+	m.counters.add(name, 1)
+	m.sink.IncrCounter(name, tags...)
+}
+
+func (m countingMetrics) ObserveDuration(name string, d time.Duration, tags ...string) {
+	m.counters.add(name+".count", 1)
+	m.counters.add(name+".seconds", d.Seconds())
+	m.sink.ObserveDuration(name, d, tags...)
+}
+
+// metrics returns the configured metrics, or a no-op implementation if there are none,
+// wrapped so the service keeps its own totals.
+func (s *slackAuth) metrics() Metrics {
+	var sink Metrics = noopMetrics{}
+	if s.metricsSink != nil {
+		sink = s.metricsSink
+	}
+	return countingMetrics{counters: &s.counters, sink: sink}
+}
+
+// MetricsSnapshot returns the totals of every counter reported by the service, without their
+// tags, along with the gauges of Stats, keyed by name. Durations are reported as their name
+// plus ".count", the number of observations, and ".seconds", their sum.
+//
+// All the counters are copied at once, so they are consistent with each other. The gauges are
+// read right after.
+func (s *slackAuth) MetricsSnapshot() map[string]float64 {
+	s.counters.mut.Lock()
+	snapshot := make(map[string]float64, len(s.counters.values)+8)
+	for name, value := range s.counters.values {
+		snapshot[name] = value
+	}
+	s.counters.mut.Unlock()
+
+	stats := s.Stats()
+	snapshot["slackauth.conns.new"] = float64(stats.NewConns)
+	snapshot["slackauth.conns.active"] = float64(stats.ActiveConns)
+	snapshot["slackauth.conns.idle"] = float64(stats.IdleConns)
+	snapshot["slackauth.conns.total"] = float64(stats.TotalConns)
+	snapshot["slackauth.auth_queue.len"] = float64(stats.AuthQueueLen)
+	snapshot["slackauth.auth_queue.cap"] = float64(stats.AuthQueueCap)
+	snapshot["slackauth.auth_queue.high_water"] = float64(stats.AuthQueueHighWater)
+	for scope, n := range stats.GrantedScopes {
+		snapshot[MetricGrantedScopes+"."+scope] = float64(n)
+	}
+	return snapshot
+}
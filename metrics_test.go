@@ -0,0 +1,92 @@
+package slackauth
+
+import (
+	"html/template"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type metricsMock struct {
+	mut       sync.Mutex
+	counters  map[string]int
+	durations map[string]int
+}
+
+func newMetricsMock() *metricsMock {
+	return &metricsMock{counters: map[string]int{}, durations: map[string]int{}}
+}
+
+func (m *metricsMock) IncrCounter(name string, tags ...string) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	m.counters[name]++
+}
+
+func (m *metricsMock) ObserveDuration(name string, d time.Duration, tags ...string) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	m.durations[name]++
+}
+
+func TestMetrics(t *testing.T) {
+	metrics := newMetricsMock()
+	auth := withConfig(&slackAuth{
+		auths:       make(chan AuthEvent, 2),
+		api:         &slackAPIMock{},
+		metricsSink: metrics,
+	}, &config{
+		successTpl: template.Must(template.New("success").Parse(tplSuccess)),
+		errorTpl:   template.Must(template.New("error").Parse(tplError)),
+		buttonTpl:  template.Must(template.New("button").Parse("button")),
+	})
+	handler := auth.handler()
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/auth?code=foo", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/auth?code=invalid", nil))
+
+	assert.Equal(t, map[string]int{
+		MetricButtonViews:   1,
+		MetricInstalls:      1,
+		MetricInstallErrors: 1,
+	}, metrics.counters)
+	assert.Equal(t, map[string]int{MetricExchangeDuration: 2}, metrics.durations)
+}
+
+func TestNoopMetrics(t *testing.T) {
+	_, ok := (&slackAuth{}).metrics().(countingMetrics).sink.(noopMetrics)
+	assert.True(t, ok)
+}
+
+func TestMetricsSnapshot(t *testing.T) {
+	auth := withConfig(&slackAuth{
+		auths: make(chan AuthEvent, 2),
+		api:   &slackAPIMock{},
+		conns: newConnStats(),
+	}, &config{
+		successTpl: template.Must(template.New("success").Parse(tplSuccess)),
+		errorTpl:   template.Must(template.New("error").Parse(tplError)),
+		buttonTpl:  template.Must(template.New("button").Parse("button")),
+	})
+	handler := auth.handler()
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/auth?code=foo", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/auth?code=invalid", nil))
+	auth.grantedScopes.add(BOT)
+
+	snapshot := auth.MetricsSnapshot()
+	assert.Equal(t, float64(1), snapshot[MetricButtonViews])
+	assert.Equal(t, float64(1), snapshot[MetricInstalls])
+	assert.Equal(t, float64(1), snapshot[MetricInstallErrors])
+	assert.Equal(t, float64(2), snapshot[MetricExchangeDuration+".count"])
+	assert.Equal(t, float64(1), snapshot["slackauth.auth_queue.len"])
+	assert.Equal(t, float64(2), snapshot["slackauth.auth_queue.cap"])
+	assert.Equal(t, float64(1), snapshot[MetricGrantedScopes+".bot"])
+
+	assert.Equal(t, snapshot[MetricInstalls], auth.MetricsSnapshot()[MetricInstalls])
+}
@@ -0,0 +1,143 @@
+package slackauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/nlopes/slack"
+)
+
+const (
+	// OAuthV1 exchanges the codes with the oauth.access method. It's the default.
+	OAuthV1 = "v1"
+	// OAuthV2 exchanges the codes with the oauth.v2.access method and sends users to the v2
+	// authorize endpoint.
+	OAuthV2 = "v2"
+)
+
+// DefaultAuthorizeV2BaseURL is the Slack endpoint users are sent to in order to authorize the
+// app when using OAuthV2.
+const DefaultAuthorizeV2BaseURL = "https://slack.com/oauth/v2/authorize"
+
+// ErrUnknownOAuthVersion is returned when the OAuth version is not one of OAuthV1 or OAuthV2.
+var ErrUnknownOAuthVersion = errors.New("slackauth: unknown oauth version")
+
+// validateOAuthVersion checks the given OAuth version is a known one. An empty version is
+// valid, and means OAuthV1.
+func validateOAuthVersion(version string) error {
+	switch version {
+	case "", OAuthV1, OAuthV2:
+		return nil
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownOAuthVersion, version)
+	}
+}
+
+// defaultAuthorizeBaseURL returns the authorize endpoint of the given OAuth version.
+func defaultAuthorizeBaseURL(version string) string {
+	if version == OAuthV2 {
+		return DefaultAuthorizeV2BaseURL
+	}
+	return DefaultAuthorizeBaseURL
+}
+
+// OAuthV2Team is a team or enterprise of an OAuthV2Response.
+type OAuthV2Team struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// OAuthV2AuthedUser is the user who authorized the app in an OAuthV2Response, along with the
+// user token, if user scopes were granted.
+type OAuthV2AuthedUser struct {
+	ID          string `json:"id"`
+	Scope       string `json:"scope"`
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+}
+
+// OAuthV2Response is the response of the oauth.v2.access method. The bot token and the
+// scopes granted to it are at the top level, while the user token and the user scopes are
+// in AuthedUser.
+type OAuthV2Response struct {
+	AccessToken     string                             `json:"access_token"`
+	TokenType       string                             `json:"token_type"`
+	Scope           string                             `json:"scope"`
+	BotUserID       string                             `json:"bot_user_id"`
+	AppID           string                             `json:"app_id"`
+	Team            OAuthV2Team                        `json:"team"`
+	Enterprise      OAuthV2Team                        `json:"enterprise"`
+	AuthedUser      OAuthV2AuthedUser                  `json:"authed_user"`
+	IncomingWebhook slack.OAuthResponseIncomingWebhook `json:"incoming_webhook"`
+	slack.SlackResponse
+}
+
+// v1 returns the response in the shape of the v1 one, so it goes through the same stores,
+// records and templates. The bot token goes to Bot.BotAccessToken, and the user token to
+// AccessToken, which falls back to the bot token if no user scopes were granted. Scope has
+// both the bot and the user scopes.
+func (r *OAuthV2Response) v1() *slack.OAuthResponse {
+	var scopes []string
+	for _, scope := range []string{r.Scope, r.AuthedUser.Scope} {
+		if scope != "" {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	accessToken := r.AuthedUser.AccessToken
+	if accessToken == "" {
+		accessToken = r.AccessToken
+	}
+
+	return &slack.OAuthResponse{
+		AccessToken:     accessToken,
+		Scope:           strings.Join(scopes, ","),
+		TeamName:        r.Team.Name,
+		TeamID:          r.Team.ID,
+		IncomingWebhook: r.IncomingWebhook,
+		Bot: slack.OAuthResponseBot{
+			BotUserID:      r.BotUserID,
+			BotAccessToken: r.AccessToken,
+		},
+		UserID:        r.AuthedUser.ID,
+		SlackResponse: r.SlackResponse,
+	}
+}
+
+func (w *slackAPIWrapper) GetOAuthV2Response(ctx context.Context, id, secret, code string, debug bool) (*OAuthV2Response, error) {
+	var response OAuthV2Response
+	if err := w.postExchange(ctx, "oauth.v2.access", id, secret, code, &response); err != nil {
+		return nil, err
+	}
+
+	if !response.Ok {
+		return nil, errors.New(response.Error)
+	}
+	return &response, nil
+}
+
+// oauthResponse exchanges the code with the given API using the configured OAuth version.
+// The v2 response is only returned when using OAuthV2.
+func (s *slackAuth) oauthResponse(ctx context.Context, api SlackAPI, clientID, clientSecret, code string) (*slack.OAuthResponse, *OAuthV2Response, error) {
+	if s.oauthVersion != OAuthV2 {
+		resp, err := api.GetOAuthResponse(ctx, clientID, clientSecret, code, s.debug)
+		return resp, nil, err
+	}
+
+	resp, err := api.GetOAuthV2Response(ctx, clientID, clientSecret, code, s.debug)
+	if err != nil || resp == nil {
+		return nil, nil, err
+	}
+	return resp.v1(), resp, nil
+}
+
+// userScopeParam returns the user_scope param of the authorize URL, which is only sent when
+// using OAuthV2.
+func (s *slackAuth) userScopeParam() string {
+	if s.oauthVersion != OAuthV2 {
+		return ""
+	}
+	return s.joinScopes(s.userScopes)
+}
@@ -0,0 +1,131 @@
+package slackauth
+
+import (
+	"context"
+	"errors"
+	"html/template"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/nlopes/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOAuthVersion(t *testing.T) {
+	_, err := New(Options{
+		Addr:         ":8080",
+		ClientID:     "foo",
+		ClientSecret: "bar",
+		OAuthVersion: "v3",
+	})
+	assert.True(t, errors.Is(err, ErrUnknownOAuthVersion))
+
+	assert.Nil(t, ioutil.WriteFile("valid.txt", []byte("foo"), 0777))
+	defer os.Remove("valid.txt")
+
+	svc, err := New(Options{
+		Addr:         ":8080",
+		ClientID:     "foo",
+		ClientSecret: "bar",
+		SuccessTpl:   "valid.txt",
+		ErrorTpl:     "valid.txt",
+		ButtonTpl:    "valid.txt",
+		Scopes:       []string{"chat:write"},
+		OAuthVersion: OAuthV2,
+		UserScopes:   []string{"identity.basic"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t,
+		"https://slack.com/oauth/v2/authorize?client_id=foo&scope=chat%3Awrite&user_scope=identity.basic",
+		svc.AuthorizeURL(),
+	)
+}
+
+func TestOAuthV1UserScopes(t *testing.T) {
+	auth := withConfig(&slackAuth{
+		clientID:         "foo",
+		authorizeBaseURL: DefaultAuthorizeBaseURL,
+		userScopes:       []string{"identity.basic"},
+	}, &config{scopes: "bot"})
+	assert.Equal(t, "https://slack.com/oauth/authorize?client_id=foo&scope=bot", auth.AuthorizeURL())
+}
+
+func TestOAuthV2ResponseV1(t *testing.T) {
+	resp := &OAuthV2Response{
+		AccessToken: "xoxb-1",
+		Scope:       "chat:write,commands",
+		BotUserID:   "B1",
+		Team:        OAuthV2Team{ID: "T1", Name: "Acme"},
+		AuthedUser:  OAuthV2AuthedUser{ID: "U1", Scope: "identity.basic", AccessToken: "xoxp-1"},
+	}
+
+	v1 := resp.v1()
+	assert.Equal(t, "xoxp-1", v1.AccessToken)
+	assert.Equal(t, "chat:write,commands,identity.basic", v1.Scope)
+	assert.Equal(t, "T1", v1.TeamID)
+	assert.Equal(t, "Acme", v1.TeamName)
+	assert.Equal(t, "U1", v1.UserID)
+	assert.Equal(t, slack.OAuthResponseBot{BotUserID: "B1", BotAccessToken: "xoxb-1"}, v1.Bot)
+
+	resp.AuthedUser = OAuthV2AuthedUser{ID: "U1"}
+	v1 = resp.v1()
+	assert.Equal(t, "xoxb-1", v1.AccessToken)
+	assert.Equal(t, "chat:write,commands", v1.Scope)
+}
+
+func TestGetOAuthV2Response(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/oauth.v2.access", r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		if r.PostFormValue("code") != "foo" {
+			w.Write([]byte(`{"ok":false,"error":"invalid_code"}`))
+			return
+		}
+		w.Write([]byte(`{"ok":true,"access_token":"xoxb-1","scope":"chat:write","team":{"id":"T1","name":"Acme"},"authed_user":{"id":"U1"}}`))
+	}))
+	defer srv.Close()
+
+	api := slack.SLACK_API
+	slack.SLACK_API = srv.URL + "/"
+	defer func() {
+		slack.SLACK_API = api
+	}()
+
+	w := &slackAPIWrapper{exchangeClient: srv.Client()}
+	resp, err := w.GetOAuthV2Response(context.Background(), "id", "secret", "foo", false)
+	assert.Nil(t, err)
+	assert.Equal(t, "xoxb-1", resp.AccessToken)
+	assert.Equal(t, OAuthV2Team{ID: "T1", Name: "Acme"}, resp.Team)
+	assert.Equal(t, "U1", resp.AuthedUser.ID)
+
+	_, err = w.GetOAuthV2Response(context.Background(), "id", "secret", "bar", false)
+	assert.EqualError(t, err, "invalid_code")
+}
+
+func TestOnAuthV2(t *testing.T) {
+	auth := withConfig(&slackAuth{
+		auths:        make(chan AuthEvent, 1),
+		api:          &slackAPIMock{},
+		oauthVersion: OAuthV2,
+	}, &config{
+		successTpl: template.Must(template.New("success").Parse("{{.Bot.BotAccessToken}}")),
+		errorTpl:   template.Must(template.New("error").Parse(tplError)),
+	})
+
+	w := httptest.NewRecorder()
+	auth.handler().ServeHTTP(w, httptest.NewRequest("GET", "/auth?code=foo", nil))
+	assert.Equal(t, "xoxb-1", w.Body.String())
+
+	var v1 *slack.OAuthResponse
+	var v2 *OAuthV2Response
+	auth.OnAuth(func(resp *slack.OAuthResponse) { v1 = resp })
+	auth.OnAuthV2(func(resp *OAuthV2Response) { v2 = resp })
+	auth.dispatch(<-auth.auths)
+
+	assert.Equal(t, "foo", v1.AccessToken)
+	assert.Equal(t, "xoxb-1", v2.AccessToken)
+	assert.Equal(t, "T1", v2.Team.ID)
+}
@@ -0,0 +1,70 @@
+package slackauth
+
+import (
+	"errors"
+	"net/http"
+
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+// installPath is the route that redirects users to the authorize URL when the bot is optional.
+const installPath = "/install"
+
+// includeBotParam is the form value of the install request used to choose whether the bot
+// scope is requested.
+const includeBotParam = "include_bot"
+
+// ErrNoScopes is returned when the install request ends up not requesting any scope.
+var ErrNoScopes = errors.New("slackauth: at least one scope needed")
+
+// installScopes returns the given scopes with the bot scope only if the user chose to add the
+// bot.
+func installScopes(scopes []string, includeBot bool) ([]string, error) {
+	var result []string
+	for _, scope := range scopes {
+		if scope != BOT {
+			result = append(result, scope)
+		}
+	}
+
+	if includeBot {
+		result = append(result, BOT)
+	}
+
+	if len(result) == 0 {
+		return nil, ErrNoScopes
+	}
+	return result, nil
+}
+
+func (s *slackAuth) installHandler(w http.ResponseWriter, r *http.Request) {
+	if s.gateButton(w, r) {
+		return
+	}
+
+	cfg := s.config()
+	scopes, _, err := s.requestedScopes(cfg, r)
+	if err == nil {
+		scopes, err = installScopes(scopes, r.FormValue(includeBotParam) == "true")
+	}
+	if err != nil {
+		log15.Error("error selecting scopes", "step", "select_scopes", "err", err.Error())
+		cfg.renderError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := s.setScopesCookie(w, r, scopes); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		log15.Error("error signing scopes", "step", "sign_scopes", "err", err.Error())
+		return
+	}
+
+	state, err := s.setState(w, r)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		log15.Error("error generating state", "step", "generate_state", "err", err.Error())
+		return
+	}
+
+	http.Redirect(w, r, withState(s.authorizeURL(scopes), state), http.StatusFound)
+}
@@ -0,0 +1,59 @@
+package slackauth
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstallScopes(t *testing.T) {
+	scopes, err := installScopes([]string{BOT, COMMANDS}, false)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{COMMANDS}, scopes)
+
+	scopes, err = installScopes([]string{COMMANDS}, true)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{COMMANDS, BOT}, scopes)
+
+	_, err = installScopes([]string{BOT}, false)
+	assert.Equal(t, ErrNoScopes, err)
+}
+
+func TestInstallHandler(t *testing.T) {
+	auth := withConfig(&slackAuth{
+		clientID:         "foo",
+		authorizeBaseURL: DefaultAuthorizeBaseURL,
+		scopeSetParam:    defaultScopeSetParam,
+		optionalBot:      true,
+	}, &config{
+		buttonTpl: template.Must(template.New("button").Parse("{{.InstallURL}}")),
+		errorTpl:  template.Must(template.New("error").Parse(tplError)),
+		scopes:    "bot,commands",
+	})
+	handler := auth.handler()
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, "/install", w.Body.String())
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/install?include_bot=true", nil))
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "https://slack.com/oauth/authorize?client_id=foo&scope=commands%2Cbot", w.Header().Get("Location"))
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/install", nil))
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "https://slack.com/oauth/authorize?client_id=foo&scope=commands", w.Header().Get("Location"))
+
+	withConfig(auth, &config{
+		errorTpl: template.Must(template.New("error").Parse(tplError)),
+		scopes:   "bot",
+	})
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/install", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
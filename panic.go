@@ -0,0 +1,62 @@
+package slackauth
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+// Policies supported by Options.OnCallbackPanic.
+const (
+	// PanicRecover recovers from panics in the auth handlers, logging them and moving on to
+	// the next auth event. The service keeps running, but the event that caused the panic is
+	// lost and the handler may have been left in an inconsistent state.
+	PanicRecover = "recover-and-continue"
+	// PanicCrash re-panics after logging the panic, which terminates the process so a
+	// supervisor can restart it in a clean state. Every pending auth event is lost.
+	PanicCrash = "crash"
+)
+
+// ErrUnknownPanicPolicy is returned when the callback panic policy is not one of the
+// supported policies.
+var ErrUnknownPanicPolicy = errors.New("slackauth: unknown callback panic policy")
+
+func validatePanicPolicy(policy string) error {
+	switch policy {
+	case "", PanicRecover, PanicCrash:
+		return nil
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownPanicPolicy, policy)
+	}
+}
+
+// consumeAuths dispatches the auth events until the auths channel is closed.
+func (s *slackAuth) consumeAuths() {
+	for event := range s.auths {
+		s.dispatchRecovering(event)
+	}
+}
+
+// dispatchRecovering dispatches the event to the handlers, applying the callback panic
+// policy if any of them panics.
+func (s *slackAuth) dispatchRecovering(event AuthEvent) {
+	defer s.recoverCallback()
+	s.dispatch(event)
+}
+
+// recoverCallback must be deferred by the goroutines running auth handlers. It logs the
+// panic, if any, and re-panics if the policy is PanicCrash.
+func (s *slackAuth) recoverCallback() {
+	r := recover()
+	if r == nil {
+		return
+	}
+
+	log15.Error("panic handling auth event", "step", "callback", "panic", fmt.Sprint(r), "stack", string(debug.Stack()))
+	s.metrics().IncrCounter(MetricCallbackPanics)
+	if s.panicPolicy == PanicCrash {
+		panic(r)
+	}
+}
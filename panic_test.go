@@ -0,0 +1,72 @@
+package slackauth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nlopes/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPanicPolicy(t *testing.T) {
+	assert.Nil(t, validatePanicPolicy(""))
+	assert.Nil(t, validatePanicPolicy(PanicCrash))
+	assert.ErrorIs(t, validatePanicPolicy("ignore"), ErrUnknownPanicPolicy)
+}
+
+func TestCallbackPanicRecover(t *testing.T) {
+	metrics := newMetricsMock()
+	auth := &slackAuth{auths: make(chan AuthEvent, 2), metricsSink: metrics}
+
+	handled := make(chan string, 2)
+	auth.OnAuth(func(resp *slack.OAuthResponse) {
+		if resp.AccessToken == "panic" {
+			panic("boom")
+		}
+		handled <- resp.AccessToken
+	})
+
+	auth.auths <- AuthEvent{Response: &slack.OAuthResponse{AccessToken: "panic"}}
+	auth.auths <- AuthEvent{Response: &slack.OAuthResponse{AccessToken: "foo"}}
+	close(auth.auths)
+	auth.consumeAuths()
+
+	assert.Equal(t, "foo", <-handled)
+	assert.Equal(t, 1, metrics.counters[MetricCallbackPanics])
+}
+
+func TestCallbackPanicRecoverContext(t *testing.T) {
+	auth := &slackAuth{callbackTimeout: time.Second}
+	auth.OnAuthContext(func(context.Context, AuthEvent) error {
+		panic("boom")
+	})
+
+	start := time.Now()
+	auth.dispatchRecovering(AuthEvent{Response: &slack.OAuthResponse{AccessToken: "foo"}})
+	assert.True(t, time.Since(start) < time.Second)
+}
+
+func TestCallbackPanicCrash(t *testing.T) {
+	auth := &slackAuth{panicPolicy: PanicCrash}
+	auth.OnAuth(func(*slack.OAuthResponse) {
+		panic("boom")
+	})
+
+	assert.PanicsWithValue(t, "boom", func() {
+		auth.dispatchRecovering(AuthEvent{Response: &slack.OAuthResponse{AccessToken: "foo"}})
+	})
+}
+
+func TestHandleCallbackPanicRecover(t *testing.T) {
+	metrics := newMetricsMock()
+	auth := &slackAuth{api: &slackAPIMock{}, metricsSink: metrics}
+	auth.OnAuth(func(*slack.OAuthResponse) {
+		panic("boom")
+	})
+
+	resp, err := auth.HandleCallback(context.Background(), "foo")
+	assert.Nil(t, err)
+	assert.Equal(t, "foo", resp.AccessToken)
+	assert.Equal(t, 1, metrics.counters[MetricCallbackPanics])
+}
@@ -0,0 +1,55 @@
+package slackauth
+
+import (
+	"net/http"
+)
+
+func (s *slackAuth) OnPartialScope(fn func(granted, requested []string, w http.ResponseWriter, r *http.Request) bool) {
+	s.handlersMut.Lock()
+	s.partialScopeHandler = fn
+	s.handlersMut.Unlock()
+}
+
+// missingScopes returns the requested scopes that are not in granted.
+func missingScopes(granted, requested []string) []string {
+	isGranted := make(map[string]bool, len(granted))
+	for _, scope := range granted {
+		isGranted[scope] = true
+	}
+
+	var missing []string
+	for _, scope := range requested {
+		if !isGranted[scope] {
+			missing = append(missing, scope)
+		}
+	}
+	return missing
+}
+
+// handlePartialScope calls the partial scope handler if not all the requested scopes were
+// granted and reports whether the handler wrote the response. If requested is nil, the
+// scopes selected by the request are used.
+func (s *slackAuth) handlePartialScope(cfg *config, event AuthEvent, requested []string, w http.ResponseWriter, r *http.Request) bool {
+	s.handlersMut.RLock()
+	handler := s.partialScopeHandler
+	s.handlersMut.RUnlock()
+
+	if handler == nil {
+		return false
+	}
+
+	if requested == nil {
+		var err error
+		requested, _, err = s.requestedScopes(cfg, r)
+		if err != nil {
+			requested = cfg.configuredScopes()
+		}
+	}
+
+	granted := grantedScopes(event.Response)
+	if len(missingScopes(granted, requested)) == 0 {
+		return false
+	}
+
+	return handler(granted, requested, w, r)
+}
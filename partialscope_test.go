@@ -0,0 +1,57 @@
+package slackauth
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nlopes/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMissingScopes(t *testing.T) {
+	assert.Equal(t, []string{COMMANDS}, missingScopes([]string{"identify", BOT}, []string{BOT, COMMANDS}))
+	assert.Nil(t, missingScopes([]string{"identify", BOT, COMMANDS}, []string{BOT, COMMANDS}))
+}
+
+func TestOnPartialScope(t *testing.T) {
+	stub := &slackAPIStub{resp: &slack.OAuthResponse{Scope: "identify,bot"}}
+	auth := withConfig(&slackAuth{
+		auths: make(chan AuthEvent, 4),
+		api:   stub,
+	}, &config{
+		successTpl: template.Must(template.New("success").Parse("success")),
+		errorTpl:   template.Must(template.New("error").Parse(tplError)),
+		scopes:     "bot,commands",
+	})
+
+	var granted, requested []string
+	handled := true
+	auth.OnPartialScope(func(g, req []string, w http.ResponseWriter, r *http.Request) bool {
+		granted, requested = g, req
+		if handled {
+			w.Write([]byte("partial"))
+		}
+		return handled
+	})
+
+	w := httptest.NewRecorder()
+	auth.handler().ServeHTTP(w, httptest.NewRequest("GET", "/auth?code=foo", nil))
+	assert.Equal(t, "partial", w.Body.String())
+	assert.Equal(t, []string{"identify", BOT}, granted)
+	assert.Equal(t, []string{BOT, COMMANDS}, requested)
+	assert.Len(t, auth.auths, 1)
+
+	handled = false
+	w = httptest.NewRecorder()
+	auth.handler().ServeHTTP(w, httptest.NewRequest("GET", "/auth?code=foo", nil))
+	assert.Equal(t, "success", w.Body.String())
+
+	granted = nil
+	stub.resp = &slack.OAuthResponse{Scope: "identify,bot,commands"}
+	w = httptest.NewRecorder()
+	auth.handler().ServeHTTP(w, httptest.NewRequest("GET", "/auth?code=foo", nil))
+	assert.Equal(t, "success", w.Body.String())
+	assert.Nil(t, granted)
+}
@@ -0,0 +1,20 @@
+package slackauth
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// isBrowser reports whether the request looks like it was made by a browser, that is, its
+// user agent is a browser one and it accepts HTML.
+func isBrowser(r *http.Request) bool {
+	return strings.HasPrefix(r.UserAgent(), "Mozilla/") &&
+		strings.Contains(r.Header.Get("Accept"), "text/html")
+}
+
+// writePlainTextSuccess writes a concise plain text success response for non-browser clients.
+func writePlainTextSuccess(w http.ResponseWriter, data successData) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintf(w, "OK: %s\n", data.DisplayName)
+}
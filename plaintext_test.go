@@ -0,0 +1,53 @@
+package slackauth
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nlopes/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsBrowser(t *testing.T) {
+	r := httptest.NewRequest("GET", "/auth", nil)
+	assert.False(t, isBrowser(r))
+
+	r.Header.Set("User-Agent", "curl/7.64.1")
+	r.Header.Set("Accept", "*/*")
+	assert.False(t, isBrowser(r))
+
+	r.Header.Set("User-Agent", "Mozilla/5.0 (X11; Linux x86_64) Gecko/20100101 Firefox/68.0")
+	assert.False(t, isBrowser(r))
+
+	r.Header.Set("Accept", "text/html,application/xhtml+xml")
+	assert.True(t, isBrowser(r))
+}
+
+func TestPlainTextForNonBrowsers(t *testing.T) {
+	auth := withConfig(&slackAuth{
+		auths:                   make(chan AuthEvent, 2),
+		api:                     &slackAPIStub{resp: &slack.OAuthResponse{TeamID: "T123", TeamName: "Foo"}},
+		plainTextForNonBrowsers: true,
+	}, &config{
+		successTpl: template.Must(template.New("success").Parse(tplSuccess)),
+		errorTpl:   template.Must(template.New("error").Parse(tplError)),
+	})
+	handler := auth.handler()
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/auth?code=foo", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/plain; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, "OK: Foo\n", w.Body.String())
+	<-auth.auths
+
+	r := httptest.NewRequest("GET", "/auth?code=foo", nil)
+	r.Header.Set("User-Agent", "Mozilla/5.0")
+	r.Header.Set("Accept", "text/html")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, tplSuccess, w.Body.String())
+	<-auth.auths
+}
@@ -0,0 +1,150 @@
+package slackauth
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+// rateLimiter is a token bucket rate limiter.
+type rateLimiter struct {
+	rate  float64
+	burst float64
+	now   func() time.Time
+
+	mut    sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// newRateLimiter returns a limiter allowing rate requests per second, with bursts of up to
+// burst requests.
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &rateLimiter{
+		rate:   rate,
+		burst:  float64(burst),
+		now:    time.Now,
+		tokens: float64(burst),
+	}
+}
+
+// allow reports whether a request can be made now. If it can't, it returns how long to wait
+// until the next request can be made.
+func (l *rateLimiter) allow() (bool, time.Duration) {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+
+	now := l.now()
+	if !l.last.IsZero() {
+		l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	}
+	l.last = now
+
+	if l.tokens >= 1 {
+		l.tokens--
+		return true, 0
+	}
+
+	wait := (1 - l.tokens) / l.rate
+	return false, time.Duration(wait * float64(time.Second))
+}
+
+// rateLimit rejects the requests exceeding the rate limit with a 429, telling clients when to
+// retry in the Retry-After header.
+func (s *slackAuth) rateLimit(h http.Handler) http.Handler {
+	if s.limiter == nil {
+		return h
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ok, wait := s.limiter.allow()
+		if ok {
+			h.ServeHTTP(w, r)
+			return
+		}
+		s.rejectRateLimited(w, r, wait)
+	})
+}
+
+// Names of the routes that can be limited with Options.RateLimits.
+const (
+	// RouteButton is the route displaying the button.
+	RouteButton = "button"
+	// RouteAuth is the route Slack redirects to after the authorization, where the code is
+	// exchanged.
+	RouteAuth = "auth"
+)
+
+// ErrUnknownRateLimitRoute is returned when a route in the rate limits is not one of the
+// routes that can be limited.
+var ErrUnknownRateLimitRoute = errors.New("slackauth: unknown rate limit route")
+
+// newRouteLimiters returns a limiter for every route with a rate limit.
+func newRouteLimiters(limits map[string]float64, burst int) (map[string]*rateLimiter, error) {
+	limiters := make(map[string]*rateLimiter, len(limits))
+	for route, rate := range limits {
+		if route != RouteButton && route != RouteAuth {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownRateLimitRoute, route)
+		}
+
+		if rate > 0 {
+			limiters[route] = newRateLimiter(rate, burst)
+		}
+	}
+	return limiters, nil
+}
+
+// limitRoute rejects the requests to the given route exceeding its own rate limit, if it has
+// one. It's applied on top of the global rate limit.
+func (s *slackAuth) limitRoute(route string, h http.HandlerFunc) http.HandlerFunc {
+	limiter := s.routeLimiters[route]
+	if limiter == nil {
+		return h
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		ok, wait := limiter.allow()
+		if ok {
+			h(w, r)
+			return
+		}
+		s.rejectRateLimited(w, r, wait)
+	}
+}
+
+// rejectRateLimited responds with a 429, telling clients to retry after the given time.
+func (s *slackAuth) rejectRateLimited(w http.ResponseWriter, r *http.Request, wait time.Duration) {
+	retryAfter := int64(math.Ceil(wait.Seconds()))
+	s.metrics().IncrCounter(MetricRateLimited, "path:"+r.URL.Path)
+	log15.Warn("request rate limited", "step", "rate_limit", "path", r.URL.Path, "retry after", retryAfter)
+	w.Header().Set("Retry-After", strconv.FormatInt(retryAfter, 10))
+	s.config().renderRateLimited(w, retryAfter)
+}
+
+type rateLimitData struct {
+	RetryAfter int64
+}
+
+// renderRateLimited renders the rate limit template, or the error template if there is none or
+// it fails.
+func (c *config) renderRateLimited(w http.ResponseWriter, retryAfter int64) {
+	if c.rateLimitTpl == nil {
+		c.renderError(w, http.StatusTooManyRequests, "too many requests")
+		return
+	}
+
+	if err := c.render(w, http.StatusTooManyRequests, c.errorContentType, c.rateLimitTpl, rateLimitData{RetryAfter: retryAfter}); err != nil {
+		log15.Error("error displaying rate limit tpl", "step", "render_rate_limit", "err", err.Error())
+		c.renderError(w, http.StatusTooManyRequests, "too many requests")
+	}
+}
@@ -0,0 +1,104 @@
+package slackauth
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Now()
+	limiter := newRateLimiter(2, 2)
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		ok, _ := limiter.allow()
+		assert.True(t, ok)
+	}
+
+	ok, wait := limiter.allow()
+	assert.False(t, ok)
+	assert.Equal(t, 500*time.Millisecond, wait)
+
+	now = now.Add(500 * time.Millisecond)
+	ok, _ = limiter.allow()
+	assert.True(t, ok)
+}
+
+func TestRateLimitRetryAfter(t *testing.T) {
+	auth := withConfig(&slackAuth{
+		limiter: newRateLimiter(0.1, 1),
+	}, &config{
+		buttonTpl: template.Must(template.New("button").Parse(tplSlackButton)),
+		errorTpl:  template.Must(template.New("error").Parse(tplError)),
+	})
+	handler := auth.handler()
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "10", w.Header().Get("Retry-After"))
+	assert.Equal(t, tplError, w.Body.String())
+}
+
+func TestRateLimitTpl(t *testing.T) {
+	auth := withConfig(&slackAuth{
+		limiter: newRateLimiter(0.1, 1),
+	}, &config{
+		buttonTpl:    template.Must(template.New("button").Parse(tplSlackButton)),
+		errorTpl:     template.Must(template.New("error").Parse(tplError)),
+		rateLimitTpl: template.Must(template.New("rate_limit").Parse("try again in {{.RetryAfter}}s")),
+	})
+	handler := auth.handler()
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "10", w.Header().Get("Retry-After"))
+	assert.Equal(t, "try again in 10s", w.Body.String())
+}
+
+func TestRouteRateLimits(t *testing.T) {
+	_, err := newRouteLimiters(map[string]float64{"events": 1}, 1)
+	assert.ErrorIs(t, err, ErrUnknownRateLimitRoute)
+
+	limiters, err := newRouteLimiters(map[string]float64{RouteAuth: 0.1, RouteButton: 0}, 1)
+	assert.Nil(t, err)
+	auth := withConfig(&slackAuth{
+		auths:         make(chan AuthEvent, 2),
+		api:           &slackAPIMock{},
+		routeLimiters: limiters,
+	}, &config{
+		successTpl: template.Must(template.New("success").Parse(tplSuccess)),
+		buttonTpl:  template.Must(template.New("button").Parse(tplSlackButton)),
+		errorTpl:   template.Must(template.New("error").Parse(tplError)),
+	})
+	handler := auth.handler()
+
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/auth?code=foo", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/auth?code=foo", nil))
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "10", w.Header().Get("Retry-After"))
+}
@@ -0,0 +1,87 @@
+package slackauth
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+// recentPath is the route where the recent installs are served.
+const recentPath = "/debug/recent"
+
+// recentInstalls is a ring buffer with the last auth events.
+type recentInstalls struct {
+	mut    sync.Mutex
+	events []AuthEvent
+	next   int
+	full   bool
+}
+
+func newRecentInstalls(size int) *recentInstalls {
+	return &recentInstalls{events: make([]AuthEvent, size)}
+}
+
+func (ri *recentInstalls) add(event AuthEvent) {
+	ri.mut.Lock()
+	defer ri.mut.Unlock()
+	ri.events[ri.next] = redactEvent(event)
+	ri.next = (ri.next + 1) % len(ri.events)
+	if ri.next == 0 {
+		ri.full = true
+	}
+}
+
+// list returns the events in the buffer, from the oldest to the newest.
+func (ri *recentInstalls) list() []AuthEvent {
+	ri.mut.Lock()
+	defer ri.mut.Unlock()
+	if !ri.full {
+		return append([]AuthEvent(nil), ri.events[:ri.next]...)
+	}
+	return append(append([]AuthEvent(nil), ri.events[ri.next:]...), ri.events[:ri.next]...)
+}
+
+// redactEvent returns a copy of the event without tokens, webhook URLs, the request or the
+// name and email of the user, since the recent installs may be served without auth.
+func redactEvent(event AuthEvent) AuthEvent {
+	if event.Response != nil {
+		resp := *event.Response
+		resp.AccessToken = ""
+		resp.Bot.BotAccessToken = ""
+		resp.IncomingWebhook.URL = ""
+		event.Response = &resp
+	}
+	if event.ResponseV2 != nil {
+		resp := *event.ResponseV2
+		resp.AccessToken = ""
+		resp.AuthedUser.AccessToken = ""
+		resp.IncomingWebhook.URL = ""
+		event.ResponseV2 = &resp
+	}
+	event.Request = nil
+	event.Identity = Identity{UserID: event.Identity.UserID}
+	return event
+}
+
+func (s *slackAuth) RecentInstalls() []AuthEvent {
+	if s.recent == nil {
+		return nil
+	}
+	return s.recent.list()
+}
+
+// recordRecent adds the event to the recent installs, if enabled.
+func (s *slackAuth) recordRecent(event AuthEvent) {
+	if s.recent != nil {
+		s.recent.add(event)
+	}
+}
+
+func (s *slackAuth) recentHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(s.RecentInstalls()); err != nil {
+		log15.Error("error writing recent installs", "step", "render_recent", "err", err.Error())
+	}
+}
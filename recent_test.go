@@ -0,0 +1,83 @@
+package slackauth
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nlopes/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecentInstalls(t *testing.T) {
+	recent := newRecentInstalls(2)
+	for _, team := range []string{"T1", "T2", "T3"} {
+		recent.add(AuthEvent{
+			Response: &slack.OAuthResponse{TeamID: team, AccessToken: "secret"},
+			Identity: Identity{UserID: "U1", RealName: "Jane Doe", Email: "jane@example.com"},
+		})
+	}
+
+	events := recent.list()
+	assert.Len(t, events, 2)
+	assert.Equal(t, "T2", events[0].Response.TeamID)
+	assert.Equal(t, "T3", events[1].Response.TeamID)
+	assert.Equal(t, "", events[1].Response.AccessToken)
+	assert.Equal(t, Identity{UserID: "U1"}, events[1].Identity)
+}
+
+func TestRecentHandler(t *testing.T) {
+	auth := withConfig(&slackAuth{
+		auths:       make(chan AuthEvent, 2),
+		api:         &slackAPIStub{resp: &slack.OAuthResponse{AccessToken: "secret", TeamID: "T1"}},
+		recent:      newRecentInstalls(5),
+		serveRecent: true,
+	}, &config{
+		successTpl: template.Must(template.New("success").Parse(tplSuccess)),
+		errorTpl:   template.Must(template.New("error").Parse(tplError)),
+	})
+	assert.Nil(t, auth.RecentInstalls())
+
+	handler := auth.handler()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/auth?code=foo", nil))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/debug/recent", nil))
+	assert.Equal(t, 200, w.Code)
+	assert.NotContains(t, w.Body.String(), "secret")
+
+	var events []AuthEvent
+	assert.Nil(t, json.NewDecoder(w.Body).Decode(&events))
+	assert.Len(t, events, 1)
+	assert.Equal(t, "T1", events[0].Response.TeamID)
+	assert.Equal(t, "/auth", events[0].Source)
+}
+
+func TestRecentHandlerOAuthV2(t *testing.T) {
+	auth := withConfig(&slackAuth{
+		auths:        make(chan AuthEvent, 2),
+		api:          &slackAPIMock{},
+		oauthVersion: OAuthV2,
+		recent:       newRecentInstalls(5),
+		serveRecent:  true,
+	}, &config{
+		successTpl: template.Must(template.New("success").Parse(tplSuccess)),
+		errorTpl:   template.Must(template.New("error").Parse(tplError)),
+	})
+
+	handler := auth.handler()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/auth?code=foo", nil))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/debug/recent", nil))
+	assert.Equal(t, 200, w.Code)
+	assert.NotContains(t, w.Body.String(), "xoxb-1")
+	assert.NotContains(t, w.Body.String(), `"foo"`)
+
+	events := auth.RecentInstalls()
+	assert.Len(t, events, 1)
+	assert.Equal(t, "T1", events[0].ResponseV2.Team.ID)
+	assert.Equal(t, "", events[0].ResponseV2.AccessToken)
+	assert.Equal(t, "", events[0].ResponseV2.AuthedUser.AccessToken)
+}
@@ -0,0 +1,43 @@
+package slackauth
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/nlopes/slack"
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+// installRecord is the JSON line written for every successful install. Tokens are never
+// included.
+type installRecord struct {
+	Team      string    `json:"team"`
+	TeamID    string    `json:"team_id"`
+	UserID    string    `json:"user_id"`
+	Scopes    string    `json:"scopes"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// recordWriter writes install records as JSON lines.
+type recordWriter struct {
+	mut sync.Mutex
+	w   io.Writer
+}
+
+func (rw *recordWriter) write(resp *slack.OAuthResponse) {
+	record := installRecord{
+		Team:      resp.TeamName,
+		TeamID:    resp.TeamID,
+		UserID:    resp.UserID,
+		Scopes:    resp.Scope,
+		Timestamp: time.Now().UTC(),
+	}
+
+	rw.mut.Lock()
+	defer rw.mut.Unlock()
+	if err := json.NewEncoder(rw.w).Encode(record); err != nil {
+		log15.Error("error writing install record", "step", "write_record", "err", err.Error())
+	}
+}
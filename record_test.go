@@ -0,0 +1,37 @@
+package slackauth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/nlopes/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmitInstallJSON(t *testing.T) {
+	var buf bytes.Buffer
+	auth := &slackAuth{
+		api: &slackAPIStub{resp: &slack.OAuthResponse{
+			AccessToken: "xoxp-secret",
+			TeamName:    "Foo",
+			TeamID:      "T123",
+			UserID:      "U123",
+			Scope:       "bot,commands",
+		}},
+		records: &recordWriter{w: &buf},
+	}
+
+	_, err := auth.HandleCallback(context.Background(), "foo")
+	assert.Nil(t, err)
+	assert.NotContains(t, buf.String(), "xoxp-secret")
+
+	var record installRecord
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "Foo", record.Team)
+	assert.Equal(t, "T123", record.TeamID)
+	assert.Equal(t, "U123", record.UserID)
+	assert.Equal(t, "bot,commands", record.Scopes)
+	assert.False(t, record.Timestamp.IsZero())
+}
@@ -0,0 +1,53 @@
+package slackauth
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"net/http"
+
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+// DefaultMaxResponseBytes is the maximum size of a rendered page, unless another one is
+// configured.
+const DefaultMaxResponseBytes = 1 << 20
+
+// ErrResponseTooLarge is returned when a rendered page is larger than the maximum response
+// size.
+var ErrResponseTooLarge = errors.New("slackauth: rendered response is too large")
+
+// limitedBuffer is a buffer that refuses to grow over max bytes.
+type limitedBuffer struct {
+	bytes.Buffer
+	max int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > b.max {
+		return 0, ErrResponseTooLarge
+	}
+	return b.Buffer.Write(p)
+}
+
+// render executes the template into a buffer and only writes the response, with the given
+// status and content type, if it succeeded, so a failed or oversized render never reaches
+// the client half written. Nothing is written if it returns an error.
+func (c *config) render(w http.ResponseWriter, status int, contentType string, tpl *template.Template, data interface{}) error {
+	max := c.maxResponseBytes
+	if max <= 0 {
+		max = DefaultMaxResponseBytes
+	}
+
+	buf := &limitedBuffer{max: max}
+	if err := tpl.Execute(buf, data); err != nil {
+		return err
+	}
+
+	setContentType(w, contentType)
+	w.WriteHeader(status)
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		log15.Debug("error writing response", "step", "write_response", "err", err.Error())
+	}
+	return nil
+}
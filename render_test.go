@@ -0,0 +1,51 @@
+package slackauth
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRender(t *testing.T) {
+	cfg := &config{maxResponseBytes: 10}
+	tpl := template.Must(template.New("").Parse(`{{range .}}{{.}}{{end}}`))
+
+	w := httptest.NewRecorder()
+	assert.Nil(t, cfg.render(w, http.StatusCreated, "text/plain", tpl, []string{"foo", "bar"}))
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "text/plain", w.Header().Get("Content-Type"))
+	assert.Equal(t, "foobar", w.Body.String())
+
+	w = httptest.NewRecorder()
+	err := cfg.render(w, http.StatusOK, "", tpl, []string{"foo", "bar", "baz", "qux"})
+	assert.ErrorIs(t, err, ErrResponseTooLarge)
+	assert.Equal(t, "", w.Header().Get("Content-Type"))
+	assert.Equal(t, "", w.Body.String())
+}
+
+func TestMaxResponseBytes(t *testing.T) {
+	auth := withConfig(&slackAuth{}, &config{
+		buttonTpl:        template.Must(template.New("button").Parse(strings.Repeat("button", 100))),
+		errorTpl:         template.Must(template.New("error").Parse("error: {{.Error}}")),
+		maxResponseBytes: 100,
+	})
+
+	w := httptest.NewRecorder()
+	auth.handler().ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, "error: the install page could not be displayed", w.Body.String())
+}
+
+func TestRenderErrorFallback(t *testing.T) {
+	cfg := &config{errorTpl: template.Must(template.New("error").Parse(`{{template "missing"}}`))}
+
+	w := httptest.NewRecorder()
+	cfg.renderError(w, http.StatusBadRequest, "invalid code")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "text/plain; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, "invalid code\n", w.Body.String())
+}
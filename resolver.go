@@ -0,0 +1,21 @@
+package slackauth
+
+import (
+	"net"
+	"net/http"
+	"time"
+)
+
+// newResolverClient returns an HTTP client whose connections resolve hosts with the given
+// resolver.
+func newResolverClient(resolver *net.Resolver) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Resolver:  resolver,
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Transport: transport}
+}
@@ -0,0 +1,48 @@
+package slackauth
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/nlopes/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolverClient(t *testing.T) {
+	var resolved bool
+	resolver := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			resolved = true
+			return nil, errors.New("resolver not reachable")
+		},
+	}
+
+	_, err := newResolverClient(resolver).Get("http://slack.invalid/api/oauth.access")
+	assert.NotNil(t, err)
+	assert.True(t, resolved)
+}
+
+func TestResolverExchange(t *testing.T) {
+	var resolved bool
+	resolver := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			resolved = true
+			return nil, errors.New("resolver not reachable")
+		},
+	}
+
+	api := slack.SLACK_API
+	slack.SLACK_API = "http://slack.invalid/api/"
+	defer func() {
+		slack.SLACK_API = api
+	}()
+
+	w := &slackAPIWrapper{exchangeClient: newResolverClient(resolver)}
+	_, err := w.GetOAuthResponse(context.Background(), "id", "secret", "foo", false)
+	assert.NotNil(t, err)
+	assert.True(t, resolved)
+}
@@ -0,0 +1,48 @@
+package slackauth
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// reservedRoutes are the patterns of the routes of the service, which can't be registered with
+// HandleFunc even if the features serving them are disabled.
+var reservedRoutes = map[string]bool{
+	"/":          true,
+	"/auth":      true,
+	manifestPath: true,
+	recentPath:   true,
+	installPath:  true,
+	consentPath:  true,
+	statusPath:   true,
+}
+
+// route is an extra route registered with HandleFunc.
+type route struct {
+	pattern string
+	handler http.HandlerFunc
+}
+
+func (s *slackAuth) HandleFunc(pattern string, handler http.HandlerFunc) {
+	if reservedRoutes[pattern] {
+		panic(fmt.Sprintf("slackauth: pattern %q is reserved", pattern))
+	}
+
+	s.handlersMut.Lock()
+	defer s.handlersMut.Unlock()
+	for _, r := range s.routes {
+		if r.pattern == pattern {
+			panic(fmt.Sprintf("slackauth: pattern %q is already registered", pattern))
+		}
+	}
+	s.routes = append(s.routes, route{pattern, handler})
+}
+
+// handleRoutes registers the extra routes in the given mux.
+func (s *slackAuth) handleRoutes(mux *http.ServeMux) {
+	s.handlersMut.RLock()
+	defer s.handlersMut.RUnlock()
+	for _, r := range s.routes {
+		mux.HandleFunc(r.pattern, r.handler)
+	}
+}
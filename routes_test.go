@@ -0,0 +1,42 @@
+package slackauth
+
+import (
+	"html/template"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleFunc(t *testing.T) {
+	auth := withConfig(&slackAuth{}, &config{
+		buttonTpl: template.Must(template.New("button").Parse("button")),
+	})
+	auth.HandleFunc("/privacy", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "privacy")
+	})
+	handler := auth.handler()
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/privacy", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "privacy", w.Body.String())
+	assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, "button", w.Body.String())
+}
+
+func TestHandleFuncReserved(t *testing.T) {
+	auth := &slackAuth{}
+	noop := func(http.ResponseWriter, *http.Request) {}
+
+	assert.Panics(t, func() { auth.HandleFunc("/auth", noop) })
+	assert.Panics(t, func() { auth.HandleFunc(consentPath, noop) })
+
+	auth.HandleFunc("/uninstall", noop)
+	assert.Panics(t, func() { auth.HandleFunc("/uninstall", noop) })
+}
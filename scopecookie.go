@@ -0,0 +1,67 @@
+package slackauth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// scopesCookie is the cookie where the scopes requested at button-click time are recorded,
+// signed with the ParamSigner.
+const scopesCookie = "slackauth_scopes"
+
+// scopesCookieMaxAge is the time users have to complete the authorization after choosing the
+// scopes.
+const scopesCookieMaxAge = time.Hour
+
+// ErrInvalidScopesCookie is returned when the cookie recording the requested scopes has been
+// tampered with.
+var ErrInvalidScopesCookie = errors.New("slackauth: invalid requested scopes")
+
+// unrequestedScopes are scopes Slack grants even if they were not requested.
+var unrequestedScopes = map[string]bool{"identify": true}
+
+// setScopesCookie records the given requested scopes in a signed cookie, if there is a
+// ParamSigner.
+func (s *slackAuth) setScopesCookie(w http.ResponseWriter, r *http.Request, scopes []string) error {
+	if s.signer == nil {
+		return nil
+	}
+	return s.setSignedCookie(w, r, scopesCookie, strings.Join(scopes, ","), scopesCookieMaxAge)
+}
+
+// verifiedScopes returns the requested scopes recorded in the scopes cookie, or nil if there
+// is no cookie or no ParamSigner.
+func (s *slackAuth) verifiedScopes(r *http.Request) ([]string, error) {
+	if s.signer == nil {
+		return nil, nil
+	}
+
+	scope, ok, err := s.signedCookie(r, scopesCookie)
+	if err != nil || (ok && scope == "") {
+		return nil, ErrInvalidScopesCookie
+	}
+
+	if !ok {
+		return nil, nil
+	}
+	return strings.Split(scope, ","), nil
+}
+
+// checkGrantedScopes checks Slack did not grant any scope that was not requested, which means
+// the authorize URL was tampered with.
+func checkGrantedScopes(granted, requested []string) error {
+	isRequested := make(map[string]bool, len(requested))
+	for _, scope := range requested {
+		isRequested[scope] = true
+	}
+
+	for _, scope := range granted {
+		if !isRequested[scope] && !unrequestedScopes[scope] {
+			return fmt.Errorf("slackauth: scope %q was granted but not requested", scope)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package slackauth
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nlopes/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckGrantedScopes(t *testing.T) {
+	assert.Nil(t, checkGrantedScopes([]string{"identify", BOT}, []string{BOT, COMMANDS}))
+	assert.NotNil(t, checkGrantedScopes([]string{"identify", BOT, "admin"}, []string{BOT}))
+}
+
+func TestScopesCookie(t *testing.T) {
+	stub := &slackAPIStub{resp: &slack.OAuthResponse{Scope: "identify,bot,commands"}}
+	auth := withConfig(&slackAuth{
+		auths:            make(chan AuthEvent, 2),
+		api:              stub,
+		clientID:         "foo",
+		authorizeBaseURL: DefaultAuthorizeBaseURL,
+		scopeSetParam:    defaultScopeSetParam,
+		optionalBot:      true,
+		signer:           NewHMACSigner([]byte("secret")),
+	}, &config{
+		successTpl: template.Must(template.New("success").Parse("success")),
+		errorTpl:   template.Must(template.New("error").Parse("{{.Error}}")),
+		scopes:     "bot,commands",
+	})
+	handler := auth.handler()
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/install", nil))
+	cookies := w.Result().Cookies()
+	assert.Len(t, cookies, 1)
+	cookie := cookies[0]
+	assert.Equal(t, scopesCookie, cookie.Name)
+
+	callback := func(c *http.Cookie) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/auth?code=foo", nil)
+		r.AddCookie(c)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	w = callback(cookie)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, `slackauth: scope &#34;bot&#34; was granted but not requested`, w.Body.String())
+
+	stub.resp = &slack.OAuthResponse{Scope: "identify,commands"}
+	w = callback(cookie)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, -1, w.Result().Cookies()[0].MaxAge)
+
+	tampered := *cookie
+	tampered.Value = "value=bot%2Ccommands&sig=foo"
+	w = callback(&tampered)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, ErrInvalidScopesCookie.Error(), w.Body.String())
+}
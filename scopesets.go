@@ -0,0 +1,42 @@
+package slackauth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// defaultScopeSetParam is the query param of the button request used to select a scope set.
+const defaultScopeSetParam = "plan"
+
+// ErrUnknownScopeSet is returned when the button is requested with a scope set that was not
+// configured.
+var ErrUnknownScopeSet = errors.New("slackauth: unknown scope set")
+
+func validateScopeSets(sets map[string][]string, delimiter string, maxScopeLength int) error {
+	for name, scopes := range sets {
+		if len(scopes) == 0 {
+			return fmt.Errorf("slackauth: at least one scope needed in scope set %q", name)
+		}
+
+		if err := validateScopeLength(scopes, delimiter, maxScopeLength); err != nil {
+			return fmt.Errorf("%s in scope set %q", err, name)
+		}
+	}
+	return nil
+}
+
+// requestedScopes returns the scopes requested in the button request along with the name of
+// the selected scope set, if any.
+func (s *slackAuth) requestedScopes(cfg *config, r *http.Request) ([]string, string, error) {
+	name := r.FormValue(s.scopeSetParam)
+	if name == "" {
+		return cfg.configuredScopes(), "", nil
+	}
+
+	scopes, ok := s.scopeSets[name]
+	if !ok {
+		return nil, "", ErrUnknownScopeSet
+	}
+	return scopes, name, nil
+}
@@ -0,0 +1,44 @@
+package slackauth
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScopeSets(t *testing.T) {
+	assert.NotNil(t, validateScopeSets(map[string][]string{"pro": nil}, "", DefaultMaxScopeLength))
+	assert.NotNil(t, validateScopeSets(map[string][]string{"pro": {BOT, COMMANDS}}, "", 5))
+
+	auth := withConfig(&slackAuth{
+		clientID:         "foo",
+		authorizeBaseURL: DefaultAuthorizeBaseURL,
+		scopeSets:        map[string][]string{"pro": {BOT, COMMANDS}},
+		scopeSetParam:    defaultScopeSetParam,
+	}, &config{
+		errorTpl:  template.Must(template.New("error").Parse(tplError)),
+		buttonTpl: template.Must(template.New("button").Parse("{{.ScopeSet}}:{{.Scopes}}")),
+		scopes:    BOT,
+	})
+	handler := auth.handler()
+
+	cases := []struct {
+		url    string
+		status int
+		body   string
+	}{
+		{"/", http.StatusOK, ":bot"},
+		{"/?plan=pro", http.StatusOK, "pro:bot,commands"},
+		{"/?plan=enterprise", http.StatusBadRequest, tplError},
+	}
+
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", c.url, nil))
+		assert.Equal(t, c.status, w.Code, c.url)
+		assert.Equal(t, c.body, w.Body.String(), c.url)
+	}
+}
@@ -0,0 +1,130 @@
+package slackauth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// signatureParam is the name of the parameter holding the signature of the signed params.
+const signatureParam = "sig"
+
+// ErrInvalidSignature is returned when the signature of the signed params does not match.
+var ErrInvalidSignature = errors.New("slackauth: invalid params signature")
+
+// ParamSigner signs the custom params carried through the OAuth round trip, so they can't be
+// tampered with by the user.
+type ParamSigner interface {
+	// Sign returns the signature of the given params.
+	Sign(params url.Values) (string, error)
+	// Verify returns an error if the signature does not belong to the given params.
+	Verify(params url.Values, signature string) error
+}
+
+type hmacSigner struct {
+	key []byte
+}
+
+// NewHMACSigner returns a ParamSigner that signs params with HMAC-SHA256 using the given key.
+func NewHMACSigner(key []byte) ParamSigner {
+	return &hmacSigner{key}
+}
+
+func (s *hmacSigner) mac(params url.Values) []byte {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(params.Encode()))
+	return mac.Sum(nil)
+}
+
+func (s *hmacSigner) Sign(params url.Values) (string, error) {
+	return base64.RawURLEncoding.EncodeToString(s.mac(params)), nil
+}
+
+func (s *hmacSigner) Verify(params url.Values, signature string) error {
+	sig, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(sig, s.mac(params)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// validateIdempotencyKeyParam checks the idempotency key param, if any, is one of the signed
+// params.
+func validateIdempotencyKeyParam(opts Options) error {
+	if opts.IdempotencyKeyParam == "" {
+		return nil
+	}
+
+	if opts.ParamSigner == nil {
+		return errors.New("slackauth: idempotency key param requires a param signer")
+	}
+
+	for _, name := range opts.SignedParams {
+		if name == opts.IdempotencyKeyParam {
+			return nil
+		}
+	}
+	return fmt.Errorf("slackauth: idempotency key param %q is not a signed param", opts.IdempotencyKeyParam)
+}
+
+// signedParams returns the signed params present in the request.
+func (s *slackAuth) signedParams(r *http.Request) url.Values {
+	params := url.Values{}
+	for _, name := range s.signedParamNames {
+		if v, ok := r.Form[name]; ok {
+			params[name] = v
+		}
+	}
+	return params
+}
+
+// signParams returns the signed params of the button request along with their signature,
+// encoded as a query string, or an empty string if there are none.
+func (s *slackAuth) signParams(r *http.Request) (string, error) {
+	if s.signer == nil {
+		return "", nil
+	}
+
+	if err := r.ParseForm(); err != nil {
+		return "", err
+	}
+
+	params := s.signedParams(r)
+	if len(params) == 0 {
+		return "", nil
+	}
+
+	sig, err := s.signer.Sign(params)
+	if err != nil {
+		return "", err
+	}
+
+	query := url.Values{signatureParam: {sig}}
+	for k, v := range params {
+		query[k] = v
+	}
+	return query.Encode(), nil
+}
+
+// verifyParams verifies the signed params of the authorization request and returns them.
+// Requests without signed params nor signature are considered valid.
+func (s *slackAuth) verifyParams(r *http.Request) (url.Values, error) {
+	if s.signer == nil {
+		return nil, nil
+	}
+
+	params := s.signedParams(r)
+	sig := r.FormValue(signatureParam)
+	if len(params) == 0 && sig == "" {
+		return nil, nil
+	}
+
+	if err := s.signer.Verify(params, sig); err != nil {
+		return nil, err
+	}
+	return params, nil
+}
@@ -0,0 +1,85 @@
+package slackauth
+
+import (
+	"html"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHMACSigner(t *testing.T) {
+	signer := NewHMACSigner([]byte("secret"))
+	params := url.Values{"invitation": {"1234"}}
+
+	sig, err := signer.Sign(params)
+	assert.Nil(t, err)
+	assert.Nil(t, signer.Verify(params, sig))
+	assert.Equal(t, ErrInvalidSignature, signer.Verify(url.Values{"invitation": {"4321"}}, sig))
+	assert.Equal(t, ErrInvalidSignature, NewHMACSigner([]byte("other")).Verify(params, sig))
+}
+
+func TestSignedParams(t *testing.T) {
+	auth := withConfig(&slackAuth{
+		auths:            make(chan AuthEvent, 1),
+		api:              &slackAPIMock{},
+		signer:           NewHMACSigner([]byte("secret")),
+		signedParamNames: []string{"invitation"},
+	}, &config{
+		successTpl: template.Must(template.New("success").Parse(tplSuccess)),
+		errorTpl:   template.Must(template.New("error").Parse(tplError)),
+		buttonTpl:  template.Must(template.New("button").Parse("{{.SignedParams}}")),
+	})
+	handler := auth.handler()
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/?invitation=1234&other=foo", nil))
+	signed, err := url.ParseQuery(html.UnescapeString(w.Body.String()))
+	assert.Nil(t, err)
+	assert.Equal(t, "1234", signed.Get("invitation"))
+	assert.Equal(t, "", signed.Get("other"))
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/auth?code=foo&"+signed.Encode(), nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	event := <-auth.auths
+	assert.Equal(t, "1234", event.Params.Get("invitation"))
+
+	signed.Set("invitation", "4321")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/auth?code=foo&"+signed.Encode(), nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, tplError, w.Body.String())
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	signer := NewHMACSigner([]byte("secret"))
+	auth := withConfig(&slackAuth{
+		auths:            make(chan AuthEvent, 1),
+		api:              &slackAPIMock{},
+		signer:           signer,
+		signedParamNames: []string{"key"},
+		idempotencyParam: "key",
+	}, &config{
+		successTpl: template.Must(template.New("success").Parse(tplSuccess)),
+		errorTpl:   template.Must(template.New("error").Parse(tplError)),
+	})
+
+	params := url.Values{"key": {"abc"}}
+	sig, err := signer.Sign(params)
+	assert.Nil(t, err)
+	params.Set(signatureParam, sig)
+
+	w := httptest.NewRecorder()
+	auth.handler().ServeHTTP(w, httptest.NewRequest("GET", "/auth?code=foo&"+params.Encode(), nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "abc", (<-auth.auths).IdempotencyKey)
+
+	assert.Nil(t, validateIdempotencyKeyParam(Options{}))
+	assert.Nil(t, validateIdempotencyKeyParam(Options{IdempotencyKeyParam: "key", ParamSigner: signer, SignedParams: []string{"key"}}))
+	assert.NotNil(t, validateIdempotencyKeyParam(Options{IdempotencyKeyParam: "key", SignedParams: []string{"key"}}))
+	assert.NotNil(t, validateIdempotencyKeyParam(Options{IdempotencyKeyParam: "key", ParamSigner: signer}))
+}
@@ -0,0 +1,52 @@
+package slackauth
+
+import (
+	"net/http"
+	"strings"
+)
+
+// trimSlash removes the trailing slashes of the request path so the exact routes of the
+// service, and the extra ones, match with or without them, unless StrictSlash is set. Other
+// paths are left alone, since the file server of the ButtonFS and subtree patterns redirect
+// to the path with the trailing slash.
+func (s *slackAuth) trimSlash(h http.Handler) http.Handler {
+	if s.strictSlash {
+		return h
+	}
+
+	exact := s.exactRoutes()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimRight(r.URL.Path, "/")
+		if path != r.URL.Path && exact[path] {
+			u := *r.URL
+			u.Path = path
+			u.RawPath = ""
+
+			r2 := new(http.Request)
+			*r2 = *r
+			r2.URL = &u
+			r = r2
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// exactRoutes returns the patterns of the routes of the service and the extra routes that
+// only match a single path.
+func (s *slackAuth) exactRoutes() map[string]bool {
+	exact := make(map[string]bool)
+	for pattern := range reservedRoutes {
+		if !strings.HasSuffix(pattern, "/") {
+			exact[pattern] = true
+		}
+	}
+
+	s.handlersMut.RLock()
+	defer s.handlersMut.RUnlock()
+	for _, r := range s.routes {
+		if !strings.HasSuffix(r.pattern, "/") {
+			exact[r.pattern] = true
+		}
+	}
+	return exact
+}
@@ -0,0 +1,107 @@
+package slackauth
+
+import (
+	"html/template"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrailingSlash(t *testing.T) {
+	auth := withConfig(&slackAuth{
+		auths: make(chan AuthEvent, 3),
+		api:   &slackAPIMock{},
+	}, &config{
+		successTpl: template.Must(template.New("success").Parse("success")),
+		errorTpl:   template.Must(template.New("error").Parse(tplError)),
+		buttonTpl:  template.Must(template.New("button").Parse("button")),
+	})
+
+	for _, path := range []string{"/auth?code=foo", "/auth/?code=foo", "/auth//?code=foo"} {
+		w := httptest.NewRecorder()
+		auth.handler().ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		assert.Equal(t, "success", w.Body.String(), path)
+	}
+	assert.Equal(t, "/auth", (<-auth.auths).Source)
+
+	w := httptest.NewRecorder()
+	auth.handler().ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, "button", w.Body.String())
+}
+
+func TestStrictSlash(t *testing.T) {
+	auth := withConfig(&slackAuth{
+		auths:       make(chan AuthEvent, 2),
+		api:         &slackAPIMock{},
+		strictSlash: true,
+	}, &config{
+		successTpl: template.Must(template.New("success").Parse("success")),
+		errorTpl:   template.Must(template.New("error").Parse(tplError)),
+		buttonTpl:  template.Must(template.New("button").Parse("button")),
+	})
+
+	w := httptest.NewRecorder()
+	auth.handler().ServeHTTP(w, httptest.NewRequest("GET", "/auth?code=foo", nil))
+	assert.Equal(t, "success", w.Body.String())
+
+	w = httptest.NewRecorder()
+	auth.handler().ServeHTTP(w, httptest.NewRequest("GET", "/auth/?code=foo", nil))
+	assert.Equal(t, "button", w.Body.String())
+}
+
+func TestTrailingSlashExtraRoutes(t *testing.T) {
+	auth := withConfig(&slackAuth{}, &config{
+		buttonTpl: template.Must(template.New("button").Parse("button")),
+	})
+	auth.HandleFunc("/privacy", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "privacy")
+	})
+	auth.HandleFunc("/hooks/", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "hooks "+r.URL.Path)
+	})
+	handler := auth.handler()
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/privacy/", nil))
+	assert.Equal(t, "privacy", w.Body.String())
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/hooks/", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "hooks /hooks/", w.Body.String())
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/hooks", nil))
+	assert.Equal(t, http.StatusTemporaryRedirect, w.Code)
+	assert.Equal(t, "/hooks/", w.Header().Get("Location"))
+}
+
+func TestTrailingSlashButtonFS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "slackauth")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "index.html"), []byte("button"), 0777))
+	assert.Nil(t, os.Mkdir(filepath.Join(dir, "assets"), 0777))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "assets", "style.css"), []byte("body {}"), 0777))
+
+	cfg := &config{}
+	assert.Nil(t, cfg.configureButtonFS(http.Dir(dir), "", []string{BOT}))
+	handler := withConfig(&slackAuth{buttonFS: http.Dir(dir), buttonIndex: buttonIndexPath("")}, cfg).handler()
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/assets", nil))
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "assets/", w.Header().Get("Location"))
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/assets/", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "style.css")
+}
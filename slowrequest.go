@@ -0,0 +1,49 @@
+package slackauth
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/nlopes/slack"
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+// isSlow reports whether the given duration exceeds the slow request threshold, if any.
+func (s *slackAuth) isSlow(d time.Duration) bool {
+	return s.slowRequestThreshold > 0 && d > s.slowRequestThreshold
+}
+
+// warnSlowRequest logs a warning if the request took longer than the slow request threshold.
+func (s *slackAuth) warnSlowRequest(r *http.Request, rec *statusRecorder, d time.Duration) {
+	if !s.isSlow(d) {
+		return
+	}
+
+	log15.Warn(
+		"slow request",
+		"step", "slow_request",
+		"method", r.Method,
+		"path", r.URL.Path,
+		"status", rec.status,
+		"duration", d,
+		"threshold", s.slowRequestThreshold,
+	)
+}
+
+// warnSlowExchange logs a warning if the exchange with Slack took longer than the slow
+// request threshold.
+func (s *slackAuth) warnSlowExchange(path string, resp *slack.OAuthResponse, d time.Duration) {
+	if !s.isSlow(d) {
+		return
+	}
+
+	log15.Warn(
+		"slow exchange",
+		"step", "slack_exchange",
+		"path", path,
+		"team", resp.TeamName,
+		"team id", resp.TeamID,
+		"duration", d,
+		"threshold", s.slowRequestThreshold,
+	)
+}
@@ -0,0 +1,52 @@
+package slackauth
+
+import (
+	"html/template"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nlopes/slack"
+	"github.com/stretchr/testify/assert"
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+func TestSlowRequestThreshold(t *testing.T) {
+	var mut sync.Mutex
+	var msgs []string
+	handler := log15.Root().GetHandler()
+	log15.Root().SetHandler(log15.FuncHandler(func(r *log15.Record) error {
+		if r.Lvl == log15.LvlWarn {
+			mut.Lock()
+			msgs = append(msgs, r.Msg)
+			mut.Unlock()
+		}
+		return nil
+	}))
+	defer log15.Root().SetHandler(handler)
+
+	auth := withConfig(&slackAuth{
+		auths: make(chan AuthEvent, 2),
+		api:   &slackAPIStub{resp: &slack.OAuthResponse{AccessToken: "foo", TeamID: "T1"}},
+	}, &config{
+		successTpl: template.Must(template.New("success").Parse(tplSuccess)),
+		errorTpl:   template.Must(template.New("error").Parse(tplError)),
+	})
+
+	auth.handler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/auth?code=foo", nil))
+	assert.Empty(t, msgs)
+
+	auth.slowRequestThreshold = time.Nanosecond
+	auth.handler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/auth?code=foo", nil))
+	assert.Equal(t, []string{"slow exchange", "slow request"}, msgs)
+}
+
+func TestIsSlow(t *testing.T) {
+	auth := &slackAuth{}
+	assert.False(t, auth.isSlow(time.Hour))
+
+	auth.slowRequestThreshold = time.Second
+	assert.False(t, auth.isSlow(time.Second))
+	assert.True(t, auth.isSlow(2*time.Second))
+}
@@ -0,0 +1,83 @@
+package slackauth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// stateCookie is the cookie where the state sent to Slack with the authorization request is
+// kept, to check it against the one Slack sends back.
+const stateCookie = "slackauth_state"
+
+// DefaultStateTTL is the time users have to complete the authorization after seeing the
+// button, unless another one is configured.
+const DefaultStateTTL = 10 * time.Minute
+
+// ErrInvalidState is returned when the state of the authorization request is missing or does
+// not match the one given to the user, which means the request was not started by them.
+var ErrInvalidState = errors.New("slackauth: invalid state")
+
+// newState returns a random state token.
+func newState() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// setState generates a new state and stores it in the state cookie. It returns an empty
+// state if state checking is disabled.
+func (s *slackAuth) setState(w http.ResponseWriter, r *http.Request) (string, error) {
+	if s.stateTTL <= 0 {
+		return "", nil
+	}
+
+	state, err := newState()
+	if err != nil {
+		return "", err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     stateCookie,
+		Value:    state,
+		Path:     "/",
+		MaxAge:   int(s.stateTTL / time.Second),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return state, nil
+}
+
+// withState adds the given state to the authorize URL, if there is one.
+func withState(authorizeURL, state string) string {
+	if state == "" {
+		return authorizeURL
+	}
+	return authorizeURL + "&state=" + url.QueryEscape(state)
+}
+
+// verifyState checks the state of the authorization request matches the one in the state
+// cookie, if state checking is enabled.
+func (s *slackAuth) verifyState(r *http.Request) error {
+	if s.stateTTL <= 0 {
+		return nil
+	}
+
+	cookie, err := r.Cookie(stateCookie)
+	if err != nil || cookie.Value == "" {
+		return ErrInvalidState
+	}
+
+	state := r.FormValue("state")
+	if subtle.ConstantTimeCompare([]byte(state), []byte(cookie.Value)) != 1 {
+		return ErrInvalidState
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package slackauth
+
+import (
+	"html"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestState(t *testing.T) {
+	auth := withConfig(&slackAuth{
+		clientID:         "foo",
+		authorizeBaseURL: DefaultAuthorizeBaseURL,
+		auths:            make(chan AuthEvent, 1),
+		api:              &slackAPIMock{},
+		stateTTL:         time.Minute,
+	}, &config{
+		successTpl: template.Must(template.New("success").Parse(tplSuccess)),
+		errorTpl:   template.Must(template.New("error").Parse("error: {{.Error}}")),
+		buttonTpl:  template.Must(template.New("button").Parse("{{.State}} {{.AuthorizeURL}}")),
+		scopes:     "bot",
+	})
+	handler := auth.handler()
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	cookies := w.Result().Cookies()
+	assert.Len(t, cookies, 1)
+	assert.Equal(t, stateCookie, cookies[0].Name)
+	assert.Equal(t, 60, cookies[0].MaxAge)
+
+	state := cookies[0].Value
+	parts := strings.SplitN(w.Body.String(), " ", 2)
+	assert.Equal(t, state, parts[0])
+	authorizeURL, err := url.Parse(html.UnescapeString(parts[1]))
+	assert.Nil(t, err)
+	assert.Equal(t, state, authorizeURL.Query().Get("state"))
+
+	callback := func(state string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/auth?code=foo&state="+url.QueryEscape(state), nil)
+		if cookie != nil {
+			r.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	w = callback(state, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "error: "+ErrInvalidState.Error(), w.Body.String())
+
+	assert.Equal(t, http.StatusBadRequest, callback("", cookies[0]).Code)
+	assert.Equal(t, http.StatusBadRequest, callback("other", cookies[0]).Code)
+
+	w = callback(state, cookies[0])
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, tplSuccess, w.Body.String())
+	<-auth.auths
+}
+
+func TestStateDisabled(t *testing.T) {
+	assert.Equal(t, "https://slack.com", withState("https://slack.com", ""))
+
+	auth := &slackAuth{}
+	state, err := auth.setState(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	assert.Nil(t, err)
+	assert.Equal(t, "", state)
+	assert.Nil(t, auth.verifyState(httptest.NewRequest("GET", "/auth?code=foo", nil)))
+}
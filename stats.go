@@ -0,0 +1,161 @@
+package slackauth
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"github.com/nlopes/slack"
+)
+
+// Stats contains counters about the state of the service.
+type Stats struct {
+	// NewConns is the number of connections that have been accepted but have not sent a
+	// request yet.
+	NewConns int64
+	// ActiveConns is the number of connections currently serving a request.
+	ActiveConns int64
+	// IdleConns is the number of keep-alive connections waiting for a new request.
+	IdleConns int64
+	// TotalConns is the number of connections accepted since the service started.
+	TotalConns int64
+	// AuthQueueLen is the number of auth events waiting to be handled.
+	AuthQueueLen int
+	// AuthQueueCap is the maximum number of auth events that can be waiting to be handled
+	// before new authorizations block.
+	AuthQueueCap int
+	// AuthQueueHighWater is the maximum number of auth events that have been waiting to be
+	// handled at the same time.
+	AuthQueueHighWater int64
+	// GrantedScopes is the number of successful authorizations that granted each scope. Only
+	// the configured scopes, including the ones of the scope sets, are counted. It's nil if
+	// no scope has been granted yet.
+	GrantedScopes map[string]int64
+}
+
+// connStats keeps track of the state of every connection of the server.
+type connStats struct {
+	newConns    int64
+	activeConns int64
+	idleConns   int64
+	totalConns  int64
+
+	mut   sync.Mutex
+	conns map[net.Conn]http.ConnState
+}
+
+func newConnStats() *connStats {
+	return &connStats{conns: make(map[net.Conn]http.ConnState)}
+}
+
+// track is meant to be used as the ConnState callback of an http.Server.
+func (c *connStats) track(conn net.Conn, state http.ConnState) {
+	c.mut.Lock()
+	prev, ok := c.conns[conn]
+	if state == http.StateHijacked || state == http.StateClosed {
+		delete(c.conns, conn)
+	} else {
+		c.conns[conn] = state
+	}
+	c.mut.Unlock()
+
+	if ok {
+		c.add(prev, -1)
+	}
+	c.add(state, 1)
+
+	if state == http.StateNew {
+		atomic.AddInt64(&c.totalConns, 1)
+	}
+}
+
+func (c *connStats) add(state http.ConnState, n int64) {
+	switch state {
+	case http.StateNew:
+		atomic.AddInt64(&c.newConns, n)
+	case http.StateActive:
+		atomic.AddInt64(&c.activeConns, n)
+	case http.StateIdle:
+		atomic.AddInt64(&c.idleConns, n)
+	}
+}
+
+func (c *connStats) fill(stats *Stats) {
+	stats.NewConns = atomic.LoadInt64(&c.newConns)
+	stats.ActiveConns = atomic.LoadInt64(&c.activeConns)
+	stats.IdleConns = atomic.LoadInt64(&c.idleConns)
+	stats.TotalConns = atomic.LoadInt64(&c.totalConns)
+}
+
+// trackAuthQueue updates the high-water mark of the auths queue with its current length.
+func (s *slackAuth) trackAuthQueue() {
+	n := int64(len(s.auths))
+	for {
+		max := atomic.LoadInt64(&s.authQueueHighWater)
+		if n <= max || atomic.CompareAndSwapInt64(&s.authQueueHighWater, max, n) {
+			return
+		}
+	}
+}
+
+// scopeCounter counts how many times each scope has been granted.
+type scopeCounter struct {
+	mut    sync.Mutex
+	counts map[string]int64
+}
+
+func (c *scopeCounter) add(scope string) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	if c.counts == nil {
+		c.counts = make(map[string]int64)
+	}
+	c.counts[scope]++
+}
+
+func (c *scopeCounter) fill(stats *Stats) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	if len(c.counts) == 0 {
+		return
+	}
+
+	stats.GrantedScopes = make(map[string]int64, len(c.counts))
+	for scope, n := range c.counts {
+		stats.GrantedScopes[scope] = n
+	}
+}
+
+// countGrantedScopes counts the scopes granted in the given response that are either
+// configured or part of a scope set, so the number of counters stays bounded.
+func (s *slackAuth) countGrantedScopes(resp *slack.OAuthResponse) {
+	known := make(map[string]bool)
+	if cfg := s.config(); cfg != nil {
+		for _, scope := range cfg.configuredScopes() {
+			known[scope] = true
+		}
+	}
+	for _, scopes := range s.scopeSets {
+		for _, scope := range scopes {
+			known[scope] = true
+		}
+	}
+
+	for _, scope := range grantedScopes(resp) {
+		if known[scope] {
+			s.grantedScopes.add(scope)
+			s.metrics().IncrCounter(MetricGrantedScopes, "scope:"+scope)
+		}
+	}
+}
+
+func (s *slackAuth) Stats() Stats {
+	var stats Stats
+	s.conns.fill(&stats)
+	stats.AuthQueueLen = len(s.auths)
+	stats.AuthQueueCap = cap(s.auths)
+	stats.AuthQueueHighWater = atomic.LoadInt64(&s.authQueueHighWater)
+	s.grantedScopes.fill(&stats)
+	return stats
+}
@@ -0,0 +1,69 @@
+package slackauth
+
+import (
+	"html/template"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nlopes/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConnStats(t *testing.T) {
+	auth := &slackAuth{conns: newConnStats()}
+	a, b := net.Pipe()
+	defer a.Close()
+	defer b.Close()
+
+	auth.conns.track(a, http.StateNew)
+	auth.conns.track(b, http.StateNew)
+	assert.Equal(t, Stats{NewConns: 2, TotalConns: 2}, auth.Stats())
+
+	auth.conns.track(a, http.StateActive)
+	auth.conns.track(b, http.StateActive)
+	auth.conns.track(b, http.StateIdle)
+	assert.Equal(t, Stats{ActiveConns: 1, IdleConns: 1, TotalConns: 2}, auth.Stats())
+
+	auth.conns.track(a, http.StateClosed)
+	auth.conns.track(b, http.StateClosed)
+	assert.Equal(t, Stats{TotalConns: 2}, auth.Stats())
+}
+
+func TestAuthQueueStats(t *testing.T) {
+	auth := withConfig(&slackAuth{
+		auths: make(chan AuthEvent, 2),
+		api:   &slackAPIMock{},
+		conns: newConnStats(),
+	}, &config{
+		successTpl: template.Must(template.New("success").Parse(tplSuccess)),
+		errorTpl:   template.Must(template.New("error").Parse(tplError)),
+	})
+	handler := auth.handler()
+
+	for i := 0; i < 2; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/auth?code=foo", nil))
+	}
+	<-auth.auths
+
+	stats := auth.Stats()
+	assert.Equal(t, 1, stats.AuthQueueLen)
+	assert.Equal(t, 2, stats.AuthQueueCap)
+	assert.Equal(t, int64(2), stats.AuthQueueHighWater)
+}
+
+func TestGrantedScopesStats(t *testing.T) {
+	metrics := newMetricsMock()
+	auth := withConfig(&slackAuth{
+		conns:       newConnStats(),
+		scopeSets:   map[string][]string{"pro": {COMMANDS}},
+		metricsSink: metrics,
+	}, &config{scopes: "bot,incoming-webhook"})
+	assert.Nil(t, auth.Stats().GrantedScopes)
+
+	auth.countGrantedScopes(&slack.OAuthResponse{Scope: "identify,bot,commands"})
+	auth.countGrantedScopes(&slack.OAuthResponse{Scope: "identify,bot,other"})
+	assert.Equal(t, map[string]int64{BOT: 2, COMMANDS: 1}, auth.Stats().GrantedScopes)
+	assert.Equal(t, 3, metrics.counters[MetricGrantedScopes])
+}
@@ -0,0 +1,155 @@
+package slackauth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+// statusPath is the route where the status of the install started with a state is streamed
+// as server-sent events.
+const statusPath = "/auth/status"
+
+// Statuses of an install, sent by the install status route.
+const (
+	// StatusInProgress means the user has not come back from Slack yet, or the code is being
+	// exchanged.
+	StatusInProgress = "in_progress"
+	// StatusSuccess means the app was installed.
+	StatusSuccess = "success"
+	// StatusError means the install failed.
+	StatusError = "error"
+)
+
+// statusStreamDuration is the maximum time a status stream is kept open. It must be shorter
+// than the write timeout of the server. Browsers reconnect on their own after it's closed.
+const statusStreamDuration = 2 * time.Second
+
+// ErrStatusWithoutState is returned when the install status route is enabled but the state
+// check is disabled, since installs are correlated by their state.
+var ErrStatusWithoutState = errors.New("slackauth: install status requires the state check")
+
+// installStatus is the status of an install, as sent to the clients.
+type installStatus struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// installStatuses keeps the status of the installs by state until the state expires. Entries
+// are only created by the authorization callback, never by the clients following them.
+type installStatuses struct {
+	ttl time.Duration
+
+	mut     sync.Mutex
+	entries map[string]installStatus
+	// changed is closed, and replaced, every time a status changes.
+	changed chan struct{}
+}
+
+func newInstallStatuses(ttl time.Duration) *installStatuses {
+	return &installStatuses{
+		ttl:     ttl,
+		entries: make(map[string]installStatus),
+		changed: make(chan struct{}),
+	}
+}
+
+// get returns the current status of the given state, if there is one, and a channel closed
+// when any status changes.
+func (st *installStatuses) get(state string) (installStatus, bool, <-chan struct{}) {
+	st.mut.Lock()
+	defer st.mut.Unlock()
+	status, ok := st.entries[state]
+	return status, ok, st.changed
+}
+
+// set changes the status of the given state and notifies the clients waiting for it. The
+// entry of the state is created if there is none, and removed once the state expires.
+func (st *installStatuses) set(state string, status installStatus) {
+	st.mut.Lock()
+	defer st.mut.Unlock()
+	if _, ok := st.entries[state]; !ok {
+		time.AfterFunc(st.ttl, func() {
+			st.mut.Lock()
+			delete(st.entries, state)
+			st.mut.Unlock()
+		})
+	}
+	st.entries[state] = status
+	close(st.changed)
+	st.changed = make(chan struct{})
+}
+
+// setStatus changes the status of the install started with the given state, if the install
+// status route is enabled.
+func (s *slackAuth) setStatus(state, status string, err error) {
+	if s.statuses == nil || state == "" {
+		return
+	}
+
+	st := installStatus{Status: status}
+	if err != nil {
+		st.Error = err.Error()
+	}
+	s.statuses.set(state, st)
+}
+
+// statusHandler streams the status of the install started with the state of the request as
+// server-sent events, until the install is done or the stream has been open for a while.
+// Until the callback comes in, the state must be the one in the state cookie, so only the
+// user who started the install can follow it. After that the cookie is gone, but the state,
+// which can't be guessed, is enough to read the status.
+func (s *slackAuth) statusHandler(w http.ResponseWriter, r *http.Request) {
+	cfg := s.config()
+	state := r.FormValue("state")
+	if _, ok, _ := s.statuses.get(state); !ok {
+		if err := s.verifyState(r); err != nil {
+			cfg.renderError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		cfg.renderError(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	timeout := time.NewTimer(statusStreamDuration)
+	defer timeout.Stop()
+
+	for {
+		status, ok, changed := s.statuses.get(state)
+		if !ok {
+			status = installStatus{Status: StatusInProgress}
+		}
+		data, err := json.Marshal(status)
+		if err != nil {
+			log15.Error("error encoding install status", "step", "install_status", "err", err.Error())
+			return
+		}
+
+		fmt.Fprintf(w, "event: status\ndata: %s\n\n", data)
+		flusher.Flush()
+		if status.Status != StatusInProgress {
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-timeout.C:
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package slackauth
+
+import (
+	"bytes"
+	"context"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstallStatus(t *testing.T) {
+	auth := withConfig(&slackAuth{
+		auths:    make(chan AuthEvent, 1),
+		api:      &slackAPIMock{},
+		stateTTL: time.Minute,
+		statuses: newInstallStatuses(time.Minute),
+	}, &config{
+		successTpl: template.Must(template.New("success").Parse(tplSuccess)),
+		errorTpl:   template.Must(template.New("error").Parse(tplError)),
+	})
+	handler := auth.handler()
+	cookie := &http.Cookie{Name: stateCookie, Value: "s1"}
+
+	request := func(url string) *http.Request {
+		r := httptest.NewRequest("GET", url, nil)
+		r.AddCookie(cookie)
+		return r
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/auth/status?state=s1", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	stream := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		handler.ServeHTTP(stream, request("/auth/status?state=s1"))
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, request("/auth?code=foo&state=s1"))
+	assert.Equal(t, http.StatusOK, w.Code)
+	<-auth.auths
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("status stream was not closed")
+	}
+
+	assert.Equal(t, "text/event-stream", stream.Header().Get("Content-Type"))
+	assert.Contains(t, stream.Body.String(), `data: {"status":"in_progress"}`)
+	assert.Contains(t, stream.Body.String(), `data: {"status":"success"}`)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, request("/auth?code=invalid&state=s1"))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	stream = httptest.NewRecorder()
+	handler.ServeHTTP(stream, request("/auth/status?state=s1"))
+	assert.Equal(t, "event: status\ndata: {\"status\":\"error\",\"error\":\"invalid code\"}\n\n", stream.Body.String())
+}
+
+func TestInstallStatusReconnect(t *testing.T) {
+	auth := withConfig(&slackAuth{
+		auths:    make(chan AuthEvent, 1),
+		api:      &slackAPIMock{},
+		stateTTL: time.Minute,
+		statuses: newInstallStatuses(time.Minute),
+	}, &config{
+		successTpl: template.Must(template.New("success").Parse(tplSuccess)),
+		errorTpl:   template.Must(template.New("error").Parse(tplError)),
+	})
+	handler := auth.handler()
+
+	// Following a state with its cookie doesn't create an entry for it.
+	r := httptest.NewRequest("GET", "/auth/status?state=s1", nil)
+	r.AddCookie(&http.Cookie{Name: stateCookie, Value: "s1"})
+	ctx, cancel := context.WithCancel(r.Context())
+	cancel()
+	stream := httptest.NewRecorder()
+	handler.ServeHTTP(stream, r.WithContext(ctx))
+	assert.Equal(t, "event: status\ndata: {\"status\":\"in_progress\"}\n\n", stream.Body.String())
+	assert.Len(t, auth.statuses.entries, 0)
+
+	r = httptest.NewRequest("GET", "/auth?code=foo&state=s1", nil)
+	r.AddCookie(&http.Cookie{Name: stateCookie, Value: "s1"})
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Set-Cookie"), stateCookie+"=;")
+
+	// The callback cleared the cookie, but the install can still be followed.
+	stream = httptest.NewRecorder()
+	handler.ServeHTTP(stream, httptest.NewRequest("GET", "/auth/status?state=s1", nil))
+	assert.Equal(t, http.StatusOK, stream.Code)
+	assert.Equal(t, "event: status\ndata: {\"status\":\"success\"}\n\n", stream.Body.String())
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/auth/status?state=s2", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Len(t, auth.statuses.entries, 1)
+}
+
+func TestInstallStatusAccessLog(t *testing.T) {
+	var logs bytes.Buffer
+	auth := withConfig(&slackAuth{
+		stateTTL:   time.Minute,
+		statuses:   newInstallStatuses(time.Minute),
+		accessLogs: &accessLogWriter{format: AccessLogCommon, w: &logs},
+	}, &config{
+		errorTpl: template.Must(template.New("error").Parse(tplError)),
+	})
+	auth.setStatus("s1", StatusSuccess, nil)
+
+	r := httptest.NewRequest("GET", "/auth/status?state=s1", nil)
+	r.AddCookie(&http.Cookie{Name: stateCookie, Value: "s1"})
+	stream := httptest.NewRecorder()
+	auth.handler().ServeHTTP(stream, r)
+
+	assert.Equal(t, http.StatusOK, stream.Code)
+	assert.True(t, stream.Flushed)
+	assert.Equal(t, "event: status\ndata: {\"status\":\"success\"}\n\n", stream.Body.String())
+	assert.Contains(t, logs.String(), `"GET /auth/status?state=s1 HTTP/1.1" 200`)
+}
@@ -0,0 +1,34 @@
+package slackauth
+
+// Step is something users can do after installing the app, shown on the success page.
+type Step struct {
+	// Scope is the granted scope the step is about.
+	Scope string
+	// Text is the description of the step.
+	Text string
+}
+
+// DefaultScopeSteps are the steps shown on the success page for each granted scope, unless
+// other ones are configured in ScopeSteps.
+var DefaultScopeSteps = map[string]string{
+	BOT:      "Invite the bot to a channel with /invite.",
+	WEBHOOK:  "Check the channel you picked for the messages of the app.",
+	COMMANDS: "Type / in any channel to see the commands of the app.",
+}
+
+// scopeSteps returns the steps for the given granted scopes, in the order Slack returned
+// them. Scopes without a step are skipped.
+func (s *slackAuth) scopeSteps(granted []string) []Step {
+	defs := s.stepDefs
+	if defs == nil {
+		defs = DefaultScopeSteps
+	}
+
+	var steps []Step
+	for _, scope := range granted {
+		if text, ok := defs[scope]; ok && text != "" {
+			steps = append(steps, Step{Scope: scope, Text: text})
+		}
+	}
+	return steps
+}
@@ -0,0 +1,37 @@
+package slackauth
+
+import (
+	"html/template"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nlopes/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScopeSteps(t *testing.T) {
+	auth := &slackAuth{}
+	assert.Equal(t, []Step{
+		{Scope: COMMANDS, Text: DefaultScopeSteps[COMMANDS]},
+		{Scope: BOT, Text: DefaultScopeSteps[BOT]},
+	}, auth.scopeSteps([]string{"identify", COMMANDS, BOT}))
+
+	auth.stepDefs = map[string]string{BOT: "Say hi to @acme.", COMMANDS: ""}
+	assert.Equal(t, []Step{{Scope: BOT, Text: "Say hi to @acme."}}, auth.scopeSteps([]string{COMMANDS, BOT}))
+	assert.Nil(t, auth.scopeSteps(nil))
+}
+
+func TestScopeStepsSuccessTpl(t *testing.T) {
+	auth := withConfig(&slackAuth{
+		auths:    make(chan AuthEvent, 1),
+		api:      &slackAPIStub{resp: &slack.OAuthResponse{Scope: "identify,bot,commands"}},
+		stepDefs: map[string]string{BOT: "invite", COMMANDS: "type"},
+	}, &config{
+		successTpl: template.Must(template.New("success").Parse(`{{range .Steps}}[{{.Text}}]{{end}}`)),
+		errorTpl:   template.Must(template.New("error").Parse(tplError)),
+	})
+
+	w := httptest.NewRecorder()
+	auth.handler().ServeHTTP(w, httptest.NewRequest("GET", "/auth?code=foo", nil))
+	assert.Equal(t, "[invite][type]", w.Body.String())
+}
@@ -0,0 +1,100 @@
+package slackauth
+
+import (
+	"context"
+	"net/http"
+)
+
+// startServer keeps the given server so Stop can shut it down, and reports whether it can be
+// started, which is not the case once the service has been stopped.
+func (s *slackAuth) startServer(srv *http.Server) bool {
+	s.srvMut.Lock()
+	defer s.srvMut.Unlock()
+	if s.stopped {
+		return false
+	}
+	s.srv = srv
+	return true
+}
+
+// startConsumer starts dispatching the auth events, unless it was already started.
+func (s *slackAuth) startConsumer() {
+	s.srvMut.Lock()
+	defer s.srvMut.Unlock()
+	if s.consumerDone != nil {
+		return
+	}
+
+	done := make(chan struct{})
+	s.consumerDone = done
+	go func() {
+		defer close(done)
+		s.consumeAuths()
+	}()
+}
+
+// isStopped reports whether Stop has been called.
+func (s *slackAuth) isStopped() bool {
+	s.srvMut.Lock()
+	defer s.srvMut.Unlock()
+	return s.stopped
+}
+
+// sendAuth queues the given event to be dispatched, and reports whether it was queued, which
+// is not the case once the auth events have stopped being dispatched.
+func (s *slackAuth) sendAuth(event AuthEvent) bool {
+	s.authsMut.RLock()
+	defer s.authsMut.RUnlock()
+	if s.authsClosed {
+		return false
+	}
+	s.auths <- event
+	return true
+}
+
+// closeAuths stops the dispatch of auth events once the pending ones have been handled.
+func (s *slackAuth) closeAuths() {
+	s.authsMut.Lock()
+	defer s.authsMut.Unlock()
+	if !s.authsClosed {
+		s.authsClosed = true
+		close(s.auths)
+	}
+}
+
+func (s *slackAuth) Stop(ctx context.Context) error {
+	s.stopOnce.Do(func() {
+		s.stopErr = s.stop(ctx)
+	})
+	return s.stopErr
+}
+
+// stop shuts the server down, waiting for the requests being served, and then stops the
+// dispatch of auth events once the pending ones have been handled. If the context expires
+// before the server is shut down, the auth events keep being dispatched, since requests that
+// are still being served may need to send them.
+func (s *slackAuth) stop(ctx context.Context) error {
+	s.srvMut.Lock()
+	s.stopped = true
+	srv, done := s.srv, s.consumerDone
+	s.srvMut.Unlock()
+
+	if srv != nil {
+		if err := srv.Shutdown(ctx); err != nil {
+			return err
+		}
+	}
+
+	s.closeAuths()
+	s.closeErrors()
+	if done == nil {
+		return nil
+	}
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
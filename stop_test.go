@@ -0,0 +1,108 @@
+package slackauth
+
+import (
+	"context"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nlopes/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStop(t *testing.T) {
+	auth := withConfig(&slackAuth{
+		addr:  "127.0.0.1:0",
+		auths: make(chan AuthEvent, 2),
+		conns: newConnStats(),
+	}, &config{
+		buttonTpl: template.Must(template.New("button").Parse("button")),
+		errorTpl:  template.Must(template.New("error").Parse(tplError)),
+	})
+
+	handled := make(chan string, 2)
+	auth.OnAuth(func(resp *slack.OAuthResponse) {
+		time.Sleep(10 * time.Millisecond)
+		handled <- resp.AccessToken
+	})
+
+	result := make(chan error, 1)
+	go func() { result <- auth.Run() }()
+	for i := 0; i < 50; i++ {
+		auth.srvMut.Lock()
+		started := auth.srv != nil
+		auth.srvMut.Unlock()
+		if started {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	auth.auths <- AuthEvent{Response: &slack.OAuthResponse{AccessToken: "foo"}}
+	auth.auths <- AuthEvent{Response: &slack.OAuthResponse{AccessToken: "bar"}}
+
+	var wg sync.WaitGroup
+	errs := make([]error, 4)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = auth.Stop(context.Background())
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, make([]error, 4), errs)
+	assert.Nil(t, <-result)
+	assert.Len(t, handled, 2)
+	assert.Nil(t, auth.Stop(context.Background()))
+}
+
+func TestStopBeforeRun(t *testing.T) {
+	auth := withConfig(&slackAuth{
+		addr:  "127.0.0.1:0",
+		auths: make(chan AuthEvent, 1),
+		conns: newConnStats(),
+	}, &config{})
+
+	assert.Nil(t, auth.Stop(context.Background()))
+	assert.Nil(t, auth.Run())
+}
+
+func TestStopHandler(t *testing.T) {
+	auth := withConfig(&slackAuth{
+		auths: make(chan AuthEvent, 1),
+		api:   &slackAPIMock{},
+	}, &config{
+		successTpl: template.Must(template.New("success").Parse(tplSuccess)),
+		errorTpl:   template.Must(template.New("error").Parse(tplError)),
+	})
+
+	release := make(chan struct{})
+	auth.OnAuth(func(resp *slack.OAuthResponse) { <-release })
+	handler := auth.Handler()
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/auth?code=foo", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, context.Canceled, auth.Stop(ctx))
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/auth?code=foo", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	// Events completed while stopping are dropped instead of sent to the closed channel.
+	event, err := auth.exchange(context.Background(), "foo", nil)
+	assert.Nil(t, err)
+	auth.completeAuthorization(event, httptest.NewRequest("GET", "/auth?code=foo", nil), nil)
+
+	// Stopping again returns the result of the first call.
+	close(release)
+	assert.Equal(t, context.Canceled, auth.Stop(context.Background()))
+}
@@ -0,0 +1,55 @@
+package slackauth
+
+import (
+	"context"
+	"time"
+
+	"github.com/nlopes/slack"
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+// storePingTimeout is the maximum time to wait for the TokenStore to be reachable on startup.
+const storePingTimeout = 5 * time.Second
+
+// TokenStore persists the OAuth responses of successful installs.
+type TokenStore interface {
+	// Save stores the given OAuth response.
+	Save(ctx context.Context, resp *slack.OAuthResponse) error
+	// Ping returns an error if the store is not reachable.
+	Ping(ctx context.Context) error
+	// HasTeam reports whether there is an OAuth response saved for the team with the given ID.
+	HasTeam(ctx context.Context, teamID string) (bool, error)
+}
+
+// isReinstall reports whether the team of the given response already installed the app,
+// according to the TokenStore. Lookup errors are logged and the install is considered new.
+func (s *slackAuth) isReinstall(ctx context.Context, resp *slack.OAuthResponse) bool {
+	if s.store == nil {
+		return false
+	}
+
+	ok, err := s.store.HasTeam(ctx, resp.TeamID)
+	if err != nil {
+		log15.Error("error looking up team", "step", "lookup_team", "team id", resp.TeamID, "err", err.Error())
+		return false
+	}
+	return ok
+}
+
+// pingStore checks the TokenStore, if any, is reachable.
+func (s *slackAuth) pingStore() error {
+	if s.store == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), storePingTimeout)
+	defer cancel()
+
+	if err := s.store.Ping(ctx); err != nil {
+		log15.Error("token store is not reachable", "err", err.Error())
+		return err
+	}
+
+	log15.Info("token store is reachable")
+	return nil
+}
@@ -0,0 +1,81 @@
+package slackauth
+
+import (
+	"context"
+	"errors"
+	"html/template"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nlopes/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+type tokenStoreMock struct {
+	pingErr error
+	saveErr error
+	saved   []*slack.OAuthResponse
+}
+
+func (m *tokenStoreMock) Save(ctx context.Context, resp *slack.OAuthResponse) error {
+	if m.saveErr != nil {
+		return m.saveErr
+	}
+	m.saved = append(m.saved, resp)
+	return nil
+}
+
+func (m *tokenStoreMock) Ping(ctx context.Context) error {
+	return m.pingErr
+}
+
+func (m *tokenStoreMock) HasTeam(ctx context.Context, teamID string) (bool, error) {
+	for _, resp := range m.saved {
+		if resp.TeamID == teamID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func TestTokenStore(t *testing.T) {
+	store := &tokenStoreMock{}
+	auth := &slackAuth{api: &slackAPIMock{}, store: store}
+
+	resp, err := auth.HandleCallback(context.Background(), "foo")
+	assert.Nil(t, err)
+	assert.Equal(t, []*slack.OAuthResponse{resp}, store.saved)
+}
+
+func TestRunFailsWhenStoreIsDown(t *testing.T) {
+	auth := &slackAuth{
+		addr:  ":8990",
+		store: &tokenStoreMock{pingErr: errors.New("connection refused")},
+	}
+
+	assert.NotNil(t, auth.Run())
+}
+
+func TestReinstall(t *testing.T) {
+	store := &tokenStoreMock{}
+	auth := withConfig(&slackAuth{
+		auths: make(chan AuthEvent, 2),
+		api:   &slackAPIStub{resp: &slack.OAuthResponse{TeamID: "T1", TeamName: "Foo"}},
+		store: store,
+	}, &config{
+		successTpl:   template.Must(template.New("success").Parse("Welcome {{.DisplayName}}")),
+		reinstallTpl: template.Must(template.New("reinstall").Parse("Welcome back {{.DisplayName}}")),
+		errorTpl:     template.Must(template.New("error").Parse(tplError)),
+	})
+
+	w := httptest.NewRecorder()
+	auth.handler().ServeHTTP(w, httptest.NewRequest("GET", "/auth?code=foo", nil))
+	assert.Equal(t, "Welcome Foo", w.Body.String())
+	assert.False(t, (<-auth.auths).Reinstall)
+
+	w = httptest.NewRecorder()
+	auth.handler().ServeHTTP(w, httptest.NewRequest("GET", "/auth?code=foo", nil))
+	assert.Equal(t, "Welcome back Foo", w.Body.String())
+	assert.True(t, (<-auth.auths).Reinstall)
+	assert.Len(t, store.saved, 2)
+}
@@ -0,0 +1,42 @@
+package slackauth
+
+import (
+	"context"
+	"strings"
+
+	"github.com/nlopes/slack"
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+// teamInfo returns the info of the team the app was installed on, using the bot token. It
+// returns nil if team info enrichment is disabled, there is no bot token or the call fails.
+func (s *slackAuth) teamInfo(ctx context.Context, resp *slack.OAuthResponse) *slack.TeamInfo {
+	if !s.enrichTeamInfo || resp.Bot.BotAccessToken == "" {
+		return nil
+	}
+
+	team, err := s.api.GetTeamInfo(ctx, resp.Bot.BotAccessToken)
+	if err != nil {
+		log15.Error("error getting team info", "step", "get_team_info", "team id", resp.TeamID, "err", err.Error())
+		return nil
+	}
+	return team
+}
+
+// teamDomain returns the domain of the given team, normalized if NormalizeTeamDomain is
+// enabled.
+func (s *slackAuth) teamDomain(team *slack.TeamInfo) string {
+	if team == nil {
+		return ""
+	}
+
+	if !s.normalizeTeamDomain {
+		return team.Domain
+	}
+	return normalizeDomain(team.Domain)
+}
+
+// normalizeDomain lowercases the domain and trims the spaces and dots around it.
+func normalizeDomain(domain string) string {
+	return strings.ToLower(strings.Trim(strings.TrimSpace(domain), "."))
+}
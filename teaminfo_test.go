@@ -0,0 +1,61 @@
+package slackauth
+
+import (
+	"context"
+	"html/template"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nlopes/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTeamInfo(t *testing.T) {
+	auth := &slackAuth{api: &slackAPIMock{}}
+	resp := &slack.OAuthResponse{TeamID: "T1"}
+	resp.Bot.BotAccessToken = "xoxb-1"
+	assert.Nil(t, auth.teamInfo(context.Background(), resp))
+
+	auth.enrichTeamInfo = true
+	assert.Equal(t, &slack.TeamInfo{ID: "T1", Domain: "acme"}, auth.teamInfo(context.Background(), resp))
+
+	resp.Bot.BotAccessToken = "xoxb-2"
+	assert.Nil(t, auth.teamInfo(context.Background(), resp))
+
+	resp.Bot.BotAccessToken = ""
+	assert.Nil(t, auth.teamInfo(context.Background(), resp))
+}
+
+func TestTeamInfoSuccessTpl(t *testing.T) {
+	resp := &slack.OAuthResponse{TeamID: "T1"}
+	resp.Bot.BotAccessToken = "xoxb-1"
+	auth := withConfig(&slackAuth{
+		auths:          make(chan AuthEvent, 1),
+		api:            &slackAPIStub{resp: resp},
+		enrichTeamInfo: true,
+	}, &config{
+		successTpl: template.Must(template.New("success").Parse("{{.Team.Domain}}")),
+		errorTpl:   template.Must(template.New("error").Parse(tplError)),
+	})
+
+	w := httptest.NewRecorder()
+	auth.handler().ServeHTTP(w, httptest.NewRequest("GET", "/auth?code=foo", nil))
+	assert.Equal(t, "acme", w.Body.String())
+	assert.Equal(t, "acme", (<-auth.auths).Team.Domain)
+}
+
+func TestNormalizeDomain(t *testing.T) {
+	assert.Equal(t, "acme", normalizeDomain("acme"))
+	assert.Equal(t, "acme-corp", normalizeDomain(" Acme-Corp.\n"))
+}
+
+func TestTeamDomain(t *testing.T) {
+	auth := &slackAuth{}
+	team := &slack.TeamInfo{Domain: "ACME. "}
+	assert.Equal(t, "", auth.teamDomain(nil))
+	assert.Equal(t, "ACME. ", auth.teamDomain(team))
+
+	auth.normalizeTeamDomain = true
+	assert.Equal(t, "acme", auth.teamDomain(team))
+	assert.Equal(t, "ACME. ", team.Domain)
+}
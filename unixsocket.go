@@ -0,0 +1,37 @@
+package slackauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+)
+
+// ErrNotSocket is returned when the Unix socket path exists and is not a socket.
+var ErrNotSocket = errors.New("slackauth: unix socket path is not a socket")
+
+// listenUnix listens on the given Unix socket with the given listen config, removing the socket
+// file left by a previous run, if any. Any other file at the path is left alone, and an error
+// is returned instead. The socket file is removed again when the listener is closed.
+func listenUnix(lc *net.ListenConfig, path string) (net.Listener, error) {
+	fi, err := os.Lstat(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return nil, err
+	case fi.Mode()&os.ModeSocket == 0:
+		return nil, fmt.Errorf("%w: %q", ErrNotSocket, path)
+	default:
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+
+	ln, err := lc.Listen(context.Background(), "unix", path)
+	if err != nil {
+		return nil, err
+	}
+	ln.(*net.UnixListener).SetUnlinkOnClose(true)
+	return ln, nil
+}
@@ -0,0 +1,95 @@
+package slackauth
+
+import (
+	"context"
+	"errors"
+	"html/template"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "slackauth.sock")
+	ln, err := net.Listen("unix", path)
+	assert.Nil(t, err)
+	ln.(*net.UnixListener).SetUnlinkOnClose(false)
+	assert.Nil(t, ln.Close())
+
+	auth := withConfig(&slackAuth{
+		unixSocket: path,
+		conns:      newConnStats(),
+	}, &config{
+		buttonTpl: template.Must(template.New("button").Parse("button")),
+		errorTpl:  template.Must(template.New("error").Parse(tplError)),
+	})
+	go auth.runServer()
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", path)
+		},
+	}}
+
+	var resp *http.Response
+	for i := 0; i < 50; i++ {
+		if resp, err = client.Get("http://slackauth/"); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	assert.Nil(t, err)
+	assert.Equal(t, "button", string(body))
+}
+
+func TestUnixSocketNotSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "slackauth.sock")
+	assert.Nil(t, os.WriteFile(path, []byte("foo"), 0600))
+
+	auth := withConfig(&slackAuth{
+		auths:      make(chan AuthEvent, 1),
+		unixSocket: path,
+		conns:      newConnStats(),
+	}, &config{
+		buttonTpl: template.Must(template.New("button").Parse("button")),
+		errorTpl:  template.Must(template.New("error").Parse(tplError)),
+	})
+	err := auth.Run()
+	assert.True(t, errors.Is(err, ErrNotSocket))
+
+	content, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "foo", string(content))
+}
+
+func TestUnixSocketWithAddr(t *testing.T) {
+	tpl := filepath.Join(t.TempDir(), "tpl.html")
+	assert.Nil(t, os.WriteFile(tpl, []byte("foo"), 0600))
+
+	_, err := New(Options{
+		Addr:         ":8080",
+		UnixSocket:   "slackauth.sock",
+		ClientID:     "foo",
+		ClientSecret: "bar",
+	})
+	assert.NotNil(t, err)
+
+	_, err = New(Options{
+		UnixSocket:   "slackauth.sock",
+		ClientID:     "foo",
+		ClientSecret: "bar",
+		SuccessTpl:   tpl,
+		ErrorTpl:     tpl,
+	})
+	assert.Nil(t, err)
+}
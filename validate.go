@@ -0,0 +1,34 @@
+package slackauth
+
+import (
+	"crypto/tls"
+	"net"
+)
+
+// Validate checks the given options as New and Run would, without starting the service:
+// templates, scopes and the rest of the options are validated, the address must be valid,
+// the TLS certificate and key must be readable and the TokenStore, if any, must be reachable.
+func Validate(opts Options) error {
+	s, err := New(opts)
+	if err != nil {
+		return err
+	}
+	return s.(*slackAuth).preflight()
+}
+
+// preflight runs the checks done before the server starts listening.
+func (s *slackAuth) preflight() error {
+	if s.addr != "" {
+		if _, _, err := net.SplitHostPort(s.addr); err != nil {
+			return err
+		}
+	}
+
+	if s.useTLS() {
+		if _, err := tls.LoadX509KeyPair(s.certFile, s.keyFile); err != nil {
+			return err
+		}
+	}
+
+	return s.pingStore()
+}
@@ -0,0 +1,40 @@
+package slackauth
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	tpl := filepath.Join(t.TempDir(), "tpl.html")
+	assert.Nil(t, os.WriteFile(tpl, []byte("foo"), 0600))
+
+	opts := Options{
+		Addr:         ":8080",
+		ClientID:     "foo",
+		ClientSecret: "bar",
+		SuccessTpl:   tpl,
+		ErrorTpl:     tpl,
+	}
+	assert.Nil(t, Validate(opts))
+
+	invalid := opts
+	invalid.ErrorTpl = "missing.html"
+	assert.NotNil(t, Validate(invalid))
+
+	invalid = opts
+	invalid.Addr = "localhost"
+	assert.NotNil(t, Validate(invalid))
+
+	invalid = opts
+	invalid.CertFile, invalid.KeyFile = tpl, tpl
+	assert.NotNil(t, Validate(invalid))
+
+	invalid = opts
+	invalid.TokenStore = &tokenStoreMock{pingErr: errors.New("connection refused")}
+	assert.NotNil(t, Validate(invalid))
+}
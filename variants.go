@@ -0,0 +1,102 @@
+package slackauth
+
+import (
+	"fmt"
+	"html/template"
+	"math/rand"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// DefaultVariant is the name of the button variant rendered from ButtonTpl when there are
+// ButtonVariants.
+const DefaultVariant = "default"
+
+// variantCookie is the cookie where the button variant assigned to the visitor is kept.
+const variantCookie = "slackauth_variant"
+
+// variantCookieMaxAge is the time visitors keep the same button variant.
+const variantCookieMaxAge = 30 * 24 * time.Hour
+
+// readButtonVariants reads the templates of the given button variants.
+func readButtonVariants(variants map[string]string) (map[string]*template.Template, error) {
+	if len(variants) == 0 {
+		return nil, nil
+	}
+
+	tpls := make(map[string]*template.Template, len(variants))
+	for name, file := range variants {
+		if name == "" || name == DefaultVariant {
+			return nil, fmt.Errorf("slackauth: button variants can not be named %q", name)
+		}
+
+		tpl, err := readTemplate(file)
+		if err != nil {
+			return nil, err
+		}
+		tpls[name] = tpl
+	}
+	return tpls, nil
+}
+
+// variantNames returns the names of all the button variants, including the default one,
+// sorted.
+func (c *config) variantNames() []string {
+	names := []string{DefaultVariant}
+	for name := range c.buttonVariants {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// variantTemplate returns the template of the given button variant.
+func (c *config) variantTemplate(variant string) *template.Template {
+	if tpl, ok := c.buttonVariants[variant]; ok {
+		return tpl
+	}
+	return c.buttonTpl
+}
+
+// isVariant reports whether the given name is one of the button variants.
+func (c *config) isVariant(name string) bool {
+	_, ok := c.buttonVariants[name]
+	return ok || (name == DefaultVariant && len(c.buttonVariants) > 0)
+}
+
+// buttonVariant returns the button variant assigned to the visitor, assigning one at random
+// and keeping it in a cookie if the visitor didn't have one. It returns an empty string if
+// there are no button variants.
+func (s *slackAuth) buttonVariant(w http.ResponseWriter, r *http.Request, cfg *config) string {
+	if len(cfg.buttonVariants) == 0 {
+		return ""
+	}
+
+	if variant := requestVariant(r, cfg); variant != "" {
+		return variant
+	}
+
+	names := cfg.variantNames()
+	variant := names[rand.Intn(len(names))]
+	http.SetCookie(w, &http.Cookie{
+		Name:     variantCookie,
+		Value:    variant,
+		Path:     "/",
+		MaxAge:   int(variantCookieMaxAge / time.Second),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return variant
+}
+
+// requestVariant returns the button variant the request comes from, if it's still one of the
+// configured ones.
+func requestVariant(r *http.Request, cfg *config) string {
+	cookie, err := r.Cookie(variantCookie)
+	if err != nil || !cfg.isVariant(cookie.Value) {
+		return ""
+	}
+	return cookie.Value
+}
@@ -0,0 +1,64 @@
+package slackauth
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestButtonVariants(t *testing.T) {
+	auth := withConfig(&slackAuth{
+		auths: make(chan AuthEvent, 1),
+		api:   &slackAPIMock{},
+	}, &config{
+		successTpl: template.Must(template.New("success").Parse(tplSuccess)),
+		errorTpl:   template.Must(template.New("error").Parse(tplError)),
+		buttonTpl:  template.Must(template.New("button").Parse("default:{{.Variant}}")),
+		buttonVariants: map[string]*template.Template{
+			"green": template.Must(template.New("green").Parse("green:{{.Variant}}")),
+		},
+	})
+	handler := auth.handler()
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	cookies := w.Result().Cookies()
+	assert.Len(t, cookies, 1)
+	variant := cookies[0].Value
+	assert.Contains(t, []string{DefaultVariant, "green"}, variant)
+	assert.Equal(t, variant+":"+variant, w.Body.String())
+
+	for i := 0; i < 5; i++ {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.AddCookie(cookies[0])
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		assert.Equal(t, variant+":"+variant, w.Body.String())
+		assert.Len(t, w.Result().Cookies(), 0)
+	}
+
+	r := httptest.NewRequest("GET", "/auth?code=foo", nil)
+	r.AddCookie(cookies[0])
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	assert.Equal(t, variant, (<-auth.auths).Variant)
+}
+
+func TestWithoutButtonVariants(t *testing.T) {
+	auth := withConfig(&slackAuth{}, &config{
+		errorTpl:  template.Must(template.New("error").Parse(tplError)),
+		buttonTpl: template.Must(template.New("button").Parse("button{{.Variant}}")),
+	})
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(&http.Cookie{Name: variantCookie, Value: "green"})
+	w := httptest.NewRecorder()
+	auth.handler().ServeHTTP(w, r)
+	assert.Equal(t, "button", w.Body.String())
+	assert.Len(t, w.Result().Cookies(), 0)
+
+	_, err := readButtonVariants(map[string]string{DefaultVariant: "button.html"})
+	assert.NotNil(t, err)
+}